@@ -0,0 +1,343 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mlowicki/termtodo/timeparse"
+)
+
+// maxPriority is the lowest priority which can be assigned. Priority 1 is the
+// most important one while 0 means no priority.
+const maxPriority = 3
+
+var errInvalidPriority = errors.New("invalid priority")
+
+// parsePriority parses priority given either as "!N" or "N".
+func parsePriority(token string) (int, error) {
+	p, err := strconv.Atoi(strings.TrimPrefix(token, "!"))
+	if err != nil || p < 0 || p > maxPriority {
+		return 0, errInvalidPriority
+	}
+	return p, nil
+}
+
+// isTag reports whether token is a tag like "+work". Tags must start with a
+// letter to be distinguishable from relative times like "+10m".
+func isTag(token string) bool {
+	if len(token) < 2 || token[0] != '+' {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(token[1:])
+	return unicode.IsLetter(r)
+}
+
+// addArgs holds arguments of the add command.
+type addArgs struct {
+	when     string
+	name     string
+	priority int
+	tags     []string
+	due      string
+	subtasks []string
+	catchUp  CatchUp
+	tz       string
+	every    time.Duration
+	count    int // number of todos to create, 0 if not limited.
+	until    string
+	overlap  Overlap
+}
+
+var countRe = regexp.MustCompile(`^x(\d+)$`)
+
+// parseAddArgs extracts arguments of the add command from tokens. The last
+// positional argument is the name while the preceding ones define when to
+// create todo. Options like "!1", "+work" or "due:+2h" can be placed anywhere
+// after the command name.
+func parseAddArgs(tokens []string) (addArgs, error) {
+	var args addArgs
+	var positional []string
+	options := true
+	for _, token := range tokens[1:] {
+		// Tokens after "--" aren't options, e.g. name starting with "!".
+		if options && token == "--" {
+			options = false
+			continue
+		}
+		if options {
+			ok, err := args.parseOption(token)
+			if err != nil {
+				return args, err
+			}
+			if ok {
+				continue
+			}
+		}
+		positional = append(positional, token)
+	}
+	// Time is optional for triggers recurring after completion.
+	if len(positional) < 2 && (args.every == 0 || len(positional) == 0) {
+		return args, errors.New("not enough arguments")
+	}
+	args.when = strings.Join(positional[:len(positional)-1], " ")
+	args.name = positional[len(positional)-1]
+	if tz, cron := SplitTZ(args.when); tz != "" {
+		if args.tz != "" && args.tz != tz {
+			return args, errors.New("conflicting time zones")
+		}
+		args.tz, args.when = tz, cron
+	}
+	if args.tz != "" {
+		if _, err := time.LoadLocation(args.tz); err != nil {
+			return args, fmt.Errorf("invalid time zone: %w", err)
+		}
+	}
+	return args, nil
+}
+
+// newTrigger returns trigger defined by arguments of the add command. Times
+// are relative to now.
+func (a addArgs) newTrigger(now time.Time) (Trigger, error) {
+	loc := time.Local
+	if a.tz != "" {
+		loc, _ = time.LoadLocation(a.tz)
+	}
+	// Without time the first todo is created right away.
+	t, timeErr := now, error(nil)
+	if a.when != "" {
+		t, timeErr = timeparse.Parse(a.when, now.In(loc))
+	}
+	if timeErr != nil && a.every != 0 {
+		return Trigger{}, errors.New("every: requires one-time trigger")
+	}
+	if timeErr == nil && a.count != 0 {
+		return Trigger{}, errors.New("count requires cron schedule")
+	}
+	var trigger Trigger
+	var err error
+	if timeErr != nil {
+		count := -1 // trigger indefinitely.
+		if a.count != 0 {
+			count = a.count
+		}
+		trigger, err = NewTrigger(a.name, a.when, now, count)
+		if err != nil {
+			if !looksLikeCron(a.when) {
+				err = timeErr
+			}
+			return Trigger{}, err
+		}
+	} else {
+		trigger, err = NewTrigger(
+			a.name,
+			onceCron,
+			t,
+			1, // one-time trigger.
+		)
+		if err != nil {
+			return Trigger{}, err
+		}
+	}
+	trigger.Priority = a.priority
+	trigger.Tags = a.tags
+	trigger.Subtasks = a.subtasks
+	trigger.CatchUp = a.catchUp
+	trigger.TZ = a.tz
+	trigger.Every = a.every
+	trigger.Overlap = a.overlap
+	if a.until != "" {
+		trigger.Until, err = parseUntil(a.until, now.In(loc))
+		if err != nil {
+			return Trigger{}, err
+		}
+		if trigger.Next().IsZero() {
+			return Trigger{}, errors.New("trigger ends before it fires")
+		}
+	}
+	if a.due != "" {
+		fire := trigger.Next().In(loc)
+		due, err := timeparse.Parse(a.due, fire)
+		if err != nil {
+			return Trigger{}, fmt.Errorf("invalid due: %w", err)
+		}
+		trigger.DueIn = due.Sub(fire)
+	}
+	return trigger, nil
+}
+
+// parseOption sets option given by token. It reports whether token is
+// an option.
+func (a *addArgs) parseOption(token string) (bool, error) {
+	if len(token) > 1 && token[0] == '!' {
+		p, err := parsePriority(token)
+		if err != nil {
+			return true, err
+		}
+		a.priority = p
+		return true, nil
+	}
+	if isTag(token) {
+		a.tags = append(a.tags, token[1:])
+		return true, nil
+	}
+	if strings.HasPrefix(token, "due:") {
+		a.due = token[len("due:"):]
+		return true, nil
+	}
+	if strings.HasPrefix(token, "sub:") {
+		a.subtasks = append(a.subtasks, token[len("sub:"):])
+		return true, nil
+	}
+	if strings.HasPrefix(token, "tz:") {
+		a.tz = token[len("tz:"):]
+		return true, nil
+	}
+	if match := countRe.FindStringSubmatch(token); match != nil {
+		n, err := strconv.Atoi(match[1])
+		if err != nil || n < 1 {
+			return true, errors.New("invalid count: " + token)
+		}
+		a.count = n
+		return true, nil
+	}
+	if strings.HasPrefix(token, "until:") {
+		a.until = token[len("until:"):]
+		return true, nil
+	}
+	if strings.HasPrefix(token, "every:") {
+		d, err := parseInterval(token[len("every:"):])
+		if err != nil {
+			return true, err
+		}
+		a.every = d
+		return true, nil
+	}
+	if strings.HasPrefix(token, "overlap:") {
+		o, err := ParseOverlap(token[len("overlap:"):])
+		if err != nil {
+			return true, err
+		}
+		a.overlap = o
+		return true, nil
+	}
+	if strings.HasPrefix(token, "catchup:") {
+		c, err := ParseCatchUp(token[len("catchup:"):])
+		if err != nil {
+			return true, err
+		}
+		a.catchUp = c
+		return true, nil
+	}
+	return false, nil
+}
+
+// isOption reports whether token would be taken for an option of the add
+// command.
+func isOption(token string) bool {
+	var args addArgs
+	ok, err := args.parseOption(token)
+	return ok || err != nil
+}
+
+// parseInterval parses positive duration like "3d", "25m" or "+1w".
+func parseInterval(s string) (time.Duration, error) {
+	now := time.Now()
+	t, err := timeparse.Parse("+"+strings.TrimPrefix(s, "+"), now)
+	if err != nil || !t.After(now) {
+		return 0, errors.New("invalid interval: " + s)
+	}
+	return t.Sub(now), nil
+}
+
+// parseUntil parses end of trigger's schedule. Plain date like "2026-12-31"
+// means the end of that day.
+func parseUntil(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, now.Location()); err == nil {
+		return t.AddDate(0, 0, 1), nil
+	}
+	t, err := timeparse.Parse(s, now)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid until: %w", err)
+	}
+	return t, nil
+}
+
+// addTokens returns tokens of the add command creating trigger t.
+func addTokens(t Trigger, now time.Time) []string {
+	when := t.Cron
+	if t.OneTime() {
+		when = formatTime(t.After.In(t.Location()), now.In(t.Location()))
+	}
+	positional := []string{when, t.Name}
+	if t.Every != 0 && !t.OneTime() {
+		// Waiting for todo to be done, so there's no time to define.
+		positional = []string{t.Name}
+	}
+	var tokens []string
+	if t.Priority != 0 {
+		tokens = append(tokens, fmt.Sprintf("!%d", t.Priority))
+	}
+	for _, tag := range t.Tags {
+		tokens = append(tokens, "+"+tag)
+	}
+	switch {
+	case t.DueIn < 0 && t.OneTime():
+		// Todo snoozed while overdue, it's due before the trigger fires.
+		fire := t.Next().In(t.Location())
+		tokens = append(tokens, "due:"+formatTime(fire.Add(t.DueIn), fire))
+	case t.DueIn != 0:
+		tokens = append(tokens, "due:"+formatDuration(t.DueIn))
+	}
+	for _, sub := range t.Subtasks {
+		tokens = append(tokens, "sub:"+sub)
+	}
+	if t.Count > 0 && !t.OneTime() && t.Every == 0 {
+		tokens = append(tokens, fmt.Sprintf("x%d", t.Count))
+	}
+	if !t.Until.IsZero() {
+		tokens = append(tokens, "until:"+formatTime(t.Until.In(t.Location()), now.In(t.Location())))
+	}
+	if t.Every != 0 {
+		tokens = append(tokens, "every:"+strings.TrimPrefix(formatDuration(t.Every), "+"))
+	}
+	if t.CatchUp != CatchUpOne {
+		tokens = append(tokens, "catchup:"+t.CatchUp.String())
+	}
+	if t.Overlap != OverlapNew {
+		tokens = append(tokens, "overlap:"+t.Overlap.String())
+	}
+	if t.TZ != "" {
+		tokens = append(tokens, "tz:"+t.TZ)
+	}
+	for _, token := range positional {
+		if isOption(token) {
+			return append(append(append([]string{"add"}, tokens...), "--"), positional...)
+		}
+	}
+	return append(append([]string{"add"}, positional...), tokens...)
+}
+
+// formatTime returns t in the format accepted by timeparse.Parse. Seconds
+// are given only if non-zero.
+func formatTime(t, now time.Time) string {
+	clock := "15:04"
+	if t.Second() != 0 {
+		clock = "15:04:05"
+	}
+	y1, m1, d1 := t.Date()
+	y2, m2, d2 := now.Date()
+	if y1 == y2 && m1 == m2 && d1 == d2 {
+		if !t.After(now) {
+			// Keep it today instead of rolling forward.
+			return t.Format("@-" + clock)
+		}
+		return t.Format("@" + clock)
+	}
+	return t.Format("@2006-01-02 " + clock)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseAddArgsEscape(t *testing.T) {
+	tests := []struct {
+		tokens []string
+		want   addArgs
+	}{
+		{[]string{"add", "+1h", "tea", "!1", "+work"}, addArgs{when: "+1h", name: "tea", priority: 1, tags: []string{"work"}}},
+		{[]string{"add", "+1h", "--", "!!! deploy"}, addArgs{when: "+1h", name: "!!! deploy"}},
+		{[]string{"add", "!2", "--", "+1h", "+review"}, addArgs{when: "+1h", name: "+review", priority: 2}},
+		{[]string{"add", "--", "@9:00", "x3"}, addArgs{when: "@9:00", name: "x3"}},
+	}
+
+	for _, test := range tests {
+		args, err := parseAddArgs(test.tokens)
+		if err != nil {
+			t.Errorf("parseAddArgs(%q) failed: %v", test.tokens, err)
+			continue
+		}
+		if !reflect.DeepEqual(args, test.want) {
+			t.Errorf("parseAddArgs(%q) = %+v, want: %+v", test.tokens, args, test.want)
+		}
+	}
+}
+
+func TestAddTokensRoundTrip(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+	trigger := func(name, cron string, after time.Time, count int, fn func(*Trigger)) Trigger {
+		tr, err := NewTrigger(name, cron, after, count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fn(&tr)
+		return tr
+	}
+	tests := []Trigger{
+		trigger("standup", "0 9 * * 1-5", now, 10, func(tr *Trigger) {
+			tr.Priority = 2
+			tr.Tags = []string{"work", "daily"}
+			tr.DueIn = 90 * time.Minute
+			tr.Subtasks = []string{"notes", "board"}
+			tr.Until = time.Date(2026, 5, 1, 0, 0, 0, 0, time.Local)
+			tr.CatchUp = CatchUpAll
+			tr.Overlap = OverlapBump
+		}),
+		trigger("sync with Warsaw", "0 9 * * *", now, -1, func(tr *Trigger) {
+			tr.TZ = "Europe/Warsaw"
+			tr.CatchUp = CatchUpSkip
+			tr.Overlap = OverlapSkip
+		}),
+		trigger("water plants", onceCron, now.Add(26*time.Hour), 1, func(tr *Trigger) {
+			tr.Every = 3 * 24 * time.Hour
+			tr.DueIn = 2 * time.Hour
+		}),
+		trigger("call Tokyo office", "0 9 * * *", now, -1, func(tr *Trigger) {
+			tr.TZ = "Asia/Tokyo"
+			tr.Until = time.Date(2026, 12, 25, 0, 0, 0, 0, time.Local)
+		}),
+		trigger("tea", onceCron, now.Add(90*time.Second), 1, func(tr *Trigger) {}),
+		trigger("stretch", onceCron, now.Add(-30*time.Second), 1, func(tr *Trigger) {
+			tr.DueIn = 30 * time.Second
+		}),
+		trigger("!!! deploy", onceCron, now.Add(time.Hour), 1, func(tr *Trigger) {
+			tr.Tags = []string{"ops"}
+		}),
+		// Snoozed while overdue, due time is relative to when it fires.
+		trigger("report", onceCron, now.Add(3*time.Hour), 1, func(tr *Trigger) {
+			tr.DueIn = now.Add(90 * time.Minute).Sub(tr.Next())
+		}),
+		trigger("review", onceCron, now.Add(25*time.Hour), 1, func(tr *Trigger) {
+			tr.DueIn = now.Add(-time.Hour).Sub(tr.Next())
+		}),
+	}
+
+	for _, want := range tests {
+		tokens := addTokens(want, now)
+		args, err := parseAddArgs(tokens)
+		if err != nil {
+			t.Errorf("parseAddArgs(%q) failed: %v", tokens, err)
+			continue
+		}
+		got, err := args.newTrigger(now)
+		if err != nil {
+			t.Errorf("newTrigger from %q failed: %v", tokens, err)
+			continue
+		}
+		if !got.After.Equal(want.After) || !got.Until.Equal(want.Until) {
+			t.Errorf("%q: after %v, until %v, want: %v, %v", tokens, got.After, got.Until, want.After, want.Until)
+		}
+		got.ID, got.After, got.Until = want.ID, want.After, want.Until
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q:\ngot:  %+v\nwant: %+v", tokens, got, want)
+		}
+	}
+}
@@ -11,7 +11,7 @@ type Blinkt struct {
 func (b *Blinkt) Stop() {
 }
 
-// NewBlinkt returns active Blinkt notification.
-func NewBlinkt() *Blinkt {
+// NewBlinkt returns active Blinkt notification showing pattern.
+func NewBlinkt(pattern Pattern) *Blinkt {
 	return &Blinkt{}
 }
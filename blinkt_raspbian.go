@@ -19,26 +19,24 @@ func (b *Blinkt) Stop() {
 	<-b.ch
 }
 
-// NewBlinkt returns active Blinkt notification.
-func NewBlinkt() *Blinkt {
+// NewBlinkt returns active Blinkt notification showing pattern.
+func NewBlinkt(pattern Pattern) *Blinkt {
 	ch := make(chan struct{})
 	go func() {
 		brightness := 0.5
 		bl := blinkt.NewBlinkt(brightness)
 		bl.Setup()
-		r, g, b := 150, 0, 0
-	outerloop:
-		for {
-			for _, pixel := range append(seq(0, 7), seq(6, 1)...) {
-				bl.Clear()
-				bl.SetPixel(pixel, r, g, b)
-				bl.Show()
-				select {
-				case <-time.After(100 * time.Millisecond):
-				case <-ch:
-					break outerloop
-				}
-			}
+		switch pattern {
+		case PatternAlert:
+			scan(&bl, 150, 0, 0, ch)
+		case PatternUrgent:
+			blinkAll(&bl, 150, 0, 0, ch)
+		case PatternWork:
+			solid(&bl, 0, 0, 150, ch)
+		case PatternBreak:
+			solid(&bl, 0, 150, 0, ch)
+		default:
+			<-ch
 		}
 		bl.Clear()
 		bl.Show()
@@ -46,3 +44,45 @@ func NewBlinkt() *Blinkt {
 	}()
 	return &Blinkt{ch}
 }
+
+// scan moves a single lit pixel back and forth until stop is signaled.
+func scan(bl *blinkt.Blinkt, r, g, b int, stop <-chan struct{}) {
+	for {
+		for _, pixel := range append(seq(0, 7), seq(6, 1)...) {
+			bl.Clear()
+			bl.SetPixel(pixel, r, g, b)
+			bl.Show()
+			select {
+			case <-time.After(100 * time.Millisecond):
+			case <-stop:
+				return
+			}
+		}
+	}
+}
+
+// blinkAll flashes all pixels until stop is signaled.
+func blinkAll(bl *blinkt.Blinkt, r, g, b int, stop <-chan struct{}) {
+	on := false
+	for {
+		on = !on
+		if on {
+			bl.SetAll(r, g, b)
+		} else {
+			bl.Clear()
+		}
+		bl.Show()
+		select {
+		case <-time.After(150 * time.Millisecond):
+		case <-stop:
+			return
+		}
+	}
+}
+
+// solid lights all pixels until stop is signaled.
+func solid(bl *blinkt.Blinkt, r, g, b int, stop <-chan struct{}) {
+	bl.SetAll(r, g, b)
+	bl.Show()
+	<-stop
+}
@@ -26,6 +26,23 @@ type CommandLine struct {
 	lineCellOffset   int
 	cursorByteOffset int
 	cursorCellOffset int
+	fillCh           chan string
+	SearchCh         chan Search
+	searching        bool   // whether text is a search query.
+	lastQuery        string // query n and N jump to the next or previous match of.
+}
+
+// A Search describes change of incremental search.
+type Search struct {
+	Query string // empty query ends search.
+	Step  int    // 1 or -1 moves to the next or previous match, 0 to the nearest one.
+}
+
+// NewCommandLine returns empty CommandLine.
+func NewCommandLine() *CommandLine {
+	// Buffered, so filling doesn't wait for the goroutine reading keys which
+	// may be sending a command or search at the same time.
+	return &CommandLine{fillCh: make(chan string, 1), SearchCh: make(chan Search)}
 }
 
 // Draws the CommandLine in the given location, 'h' is not used at the moment
@@ -132,6 +149,11 @@ func (eb *CommandLine) DeleteAll() {
 	eb.text = byteSliceRemove(eb.text, 0, len(eb.text))
 }
 
+// Fill replaces text being edited. Cursor is placed at the end.
+func (cl *CommandLine) Fill(text string) {
+	cl.fillCh <- text
+}
+
 func (eb *CommandLine) InsertRune(r rune) {
 	var buf [utf8.UTFMax]byte
 	n := utf8.EncodeRune(buf[:], r)
@@ -141,7 +163,11 @@ func (eb *CommandLine) InsertRune(r rune) {
 
 func (eb *CommandLine) Redraw() {
 	w, h := termbox.Size()
-	termbox.SetCell(0, h-1, ':', termbox.ColorDefault, termbox.ColorDefault)
+	prompt := ':'
+	if eb.searching {
+		prompt = '/'
+	}
+	termbox.SetCell(0, h-1, prompt, termbox.ColorDefault, termbox.ColorDefault)
 	eb.Draw(1, h-1, w-1, 1)
 	termbox.SetCursor(1+eb.CursorX(), h-1)
 }
@@ -150,7 +176,23 @@ func (cl *CommandLine) Run(events <-chan termbox.Event) <-chan []string {
 	ch := make(chan []string)
 	go func() {
 		for {
-			switch ev := <-events; ev.Type {
+			var ev termbox.Event
+			select {
+			case ev = <-events:
+			case text := <-cl.fillCh:
+				cl.DeleteAll()
+				cl.text = append(cl.text, text...)
+				cl.MoveCursorTo(len(cl.text))
+				cl.Redraw()
+				termbox.Flush()
+				continue
+			}
+			if ev.Type == termbox.EventKey && cl.search(ev) {
+				cl.Redraw()
+				termbox.Flush()
+				continue
+			}
+			switch ev.Type {
 			case termbox.EventKey:
 				switch ev.Key {
 				case termbox.KeyArrowLeft, termbox.KeyCtrlB:
@@ -166,6 +208,8 @@ func (cl *CommandLine) Run(events <-chan termbox.Event) <-chan []string {
 					cl.MoveCursorTo(0)
 				case termbox.KeyEnd, termbox.KeyCtrlE:
 					cl.MoveCursorTo(len(cl.text))
+				case termbox.KeyCtrlUnderscore:
+					ch <- []string{"undo"}
 				case termbox.KeyEnter:
 					tokens, err := gsq.Split(string(cl.text))
 					if err != nil {
@@ -174,8 +218,10 @@ func (cl *CommandLine) Run(events <-chan termbox.Event) <-chan []string {
 					if len(tokens) == 0 {
 						break
 					}
-					ch <- tokens
 					cl.DeleteAll()
+					// Command ends search.
+					cl.lastQuery = ""
+					ch <- tokens
 				default:
 					if ev.Ch != 0 {
 						cl.InsertRune(ev.Ch)
@@ -191,6 +237,59 @@ func (cl *CommandLine) Run(events <-chan termbox.Event) <-chan []string {
 	return ch
 }
 
+// search handles key event related to incremental search. It reports
+// whether event has been consumed.
+func (cl *CommandLine) search(ev termbox.Event) bool {
+	if !cl.searching {
+		if len(cl.text) > 0 {
+			return false
+		}
+		switch {
+		case ev.Ch == '/':
+			cl.searching = true
+		case ev.Ch == 'n' && cl.lastQuery != "":
+			cl.SearchCh <- Search{Query: cl.lastQuery, Step: 1}
+		case ev.Ch == 'N' && cl.lastQuery != "":
+			cl.SearchCh <- Search{Query: cl.lastQuery, Step: -1}
+		case ev.Key == termbox.KeyEsc && cl.lastQuery != "":
+			cl.lastQuery = ""
+			cl.SearchCh <- Search{}
+		default:
+			return false
+		}
+		return true
+	}
+	switch ev.Key {
+	case termbox.KeyEnter:
+		// Match stays selected for the next command.
+		cl.searching = false
+		cl.lastQuery = string(cl.text)
+		cl.DeleteAll()
+		return true
+	case termbox.KeyEsc:
+		cl.searching = false
+		cl.DeleteAll()
+		cl.SearchCh <- Search{}
+		return true
+	case termbox.KeyBackspace, termbox.KeyBackspace2:
+		if len(cl.text) == 0 {
+			cl.searching = false
+			cl.SearchCh <- Search{}
+			return true
+		}
+		cl.DeleteRuneBackward()
+	case termbox.KeySpace:
+		cl.InsertRune(' ')
+	default:
+		if ev.Ch == 0 {
+			return false
+		}
+		cl.InsertRune(ev.Ch)
+	}
+	cl.SearchCh <- Search{Query: string(cl.text)}
+	return true
+}
+
 // Please, keep in mind that cursor depends on the value of lineCellOffset, which
 // is being set on Draw() call, so.. call this method after Draw() one.
 func (eb *CommandLine) CursorX() int {
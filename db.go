@@ -4,13 +4,19 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"os"
+	"sort"
 )
 
 // A DB read / writes scheduler's data from / to disk.
 type DB struct {
 	Todos    map[string]Todo
 	Triggers map[string]Trigger
-	filename string `json:"-"`
+	History  map[string]Todo
+	Undo     []Change
+	Redo     []Change
+	Seq      int               // the last assigned short ID.
+	Views    map[string]string // saved filter queries by name.
+	filename string            `json:"-"`
 }
 
 // NewDB returns a DB located in filename.
@@ -19,6 +25,8 @@ func NewDB(filename string) (*DB, error) {
 		filename: filename,
 		Todos:    make(map[string]Todo),
 		Triggers: make(map[string]Trigger),
+		History:  make(map[string]Todo),
+		Views:    make(map[string]string),
 	}
 	err := db.Read()
 	if err != nil {
@@ -53,5 +61,87 @@ func (db *DB) Read() error {
 	if err != nil {
 		return err
 	}
+	// Items created before lists were introduced belong to the default one.
+	for id, todo := range db.Todos {
+		if todo.List == "" {
+			todo.List = defaultList
+			db.Todos[id] = todo
+		}
+	}
+	for id, trigger := range db.Triggers {
+		if trigger.List == "" {
+			trigger.List = defaultList
+			db.Triggers[id] = trigger
+		}
+	}
+	for id, todo := range db.History {
+		if todo.List == "" {
+			todo.List = defaultList
+			db.History[id] = todo
+		}
+	}
+	// Todos created before trigger kinds were stored, as long as their
+	// trigger still exists.
+	for _, todos := range []map[string]Todo{db.Todos, db.History} {
+		for id, todo := range todos {
+			if trigger, ok := db.Triggers[todo.TriggerID]; ok && todo.TriggerKind == "" {
+				todo.TriggerKind = triggerKind(trigger)
+				todos[id] = todo
+			}
+		}
+	}
+	if db.Views == nil {
+		db.Views = make(map[string]string)
+	}
+	// Items created before short IDs were introduced.
+	db.assignShortIDs()
 	return nil
 }
+
+// assignShortIDs numbers items which don't have short ID yet. Older items
+// get lower numbers.
+func (db *DB) assignShortIDs() {
+	for _, todos := range []map[string]Todo{db.History, db.Todos} {
+		var ids []string
+		for id, todo := range todos {
+			if todo.ShortID == 0 {
+				ids = append(ids, id)
+			}
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			a, b := todos[ids[i]], todos[ids[j]]
+			if !a.CreatedAt.Equal(b.CreatedAt) {
+				return a.CreatedAt.Before(b.CreatedAt)
+			}
+			if (a.Parent == "") != (b.Parent == "") {
+				return a.Parent == ""
+			}
+			return a.ID < b.ID
+		})
+		for _, id := range ids {
+			todo := todos[id]
+			db.Seq++
+			todo.ShortID = db.Seq
+			todos[id] = todo
+		}
+	}
+	var ids []string
+	for id, trigger := range db.Triggers {
+		if trigger.ShortID == 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := db.Triggers[ids[i]], db.Triggers[ids[j]]
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	for _, id := range ids {
+		trigger := db.Triggers[id]
+		db.Seq++
+		trigger.ShortID = db.Seq
+		db.Triggers[id] = trigger
+	}
+}
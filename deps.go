@@ -0,0 +1,51 @@
+package main
+
+import "errors"
+
+var errCycle = errors.New("dependency cycle")
+
+// dependsOn reports whether todo id is blocked, directly or not, by todo
+// blocker.
+func dependsOn(todos map[string]Todo, id, blocker string) bool {
+	visited := make(map[string]bool)
+	var visit func(id string) bool
+	visit = func(id string) bool {
+		if id == blocker {
+			return true
+		}
+		if visited[id] {
+			return false
+		}
+		visited[id] = true
+		for _, b := range todos[id].BlockedBy {
+			if visit(b) {
+				return true
+			}
+		}
+		return false
+	}
+	return visit(id)
+}
+
+// block makes todo id blocked by todo blocker. It fails if blocker already
+// depends on todo id.
+func block(todos map[string]Todo, id, blocker string) error {
+	if dependsOn(todos, blocker, id) {
+		return errCycle
+	}
+	todo := todos[id]
+	todo.BlockedBy = addBlocker(todo.BlockedBy, blocker)
+	todos[id] = todo
+	return nil
+}
+
+// addBlocker returns blockedBy with blocker added unless it's already there.
+// The slice passed isn't modified.
+func addBlocker(blockedBy []string, blocker string) []string {
+	for _, b := range blockedBy {
+		if b == blocker {
+			return blockedBy
+		}
+	}
+	return append(append([]string(nil), blockedBy...), blocker)
+}
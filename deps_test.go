@@ -0,0 +1,77 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBlock(t *testing.T) {
+	todos := map[string]Todo{
+		"a": {ID: "a"},
+		"b": {ID: "b", BlockedBy: []string{"a"}},
+		"c": {ID: "c", BlockedBy: []string{"b"}},
+		"d": {ID: "d"},
+	}
+	tests := []struct {
+		id, blocker string
+		want        error
+	}{
+		{"d", "c", nil},
+		{"a", "a", errCycle},
+		{"a", "b", errCycle},
+		{"a", "c", errCycle},
+		{"c", "a", nil},
+		{"c", "b", nil},
+	}
+
+	for _, test := range tests {
+		if err := block(todos, test.id, test.blocker); err != test.want {
+			t.Errorf("block(%s, %s) = %v, want: %v", test.id, test.blocker, err, test.want)
+		}
+	}
+	if want := []string{"b", "a"}; !reflect.DeepEqual(todos["c"].BlockedBy, want) {
+		t.Errorf("c blocked by %v, want: %v", todos["c"].BlockedBy, want)
+	}
+	if !dependsOn(todos, "d", "a") || dependsOn(todos, "a", "d") {
+		t.Error("d should depend on a, but not the other way round")
+	}
+}
+
+func TestUnblock(t *testing.T) {
+	sch := &Scheduler{db: newTestDB()}
+	sch.db.Todos["a"] = Todo{ID: "a", Name: "a"}
+	sch.db.Todos["b"] = Todo{ID: "b", Name: "b"}
+	sch.db.Todos["c"] = Todo{ID: "c", Name: "c", BlockedBy: []string{"a", "b"}, Notify: true}
+
+	sch.completeTodo("a", time.Now())
+	if want := []string{"b"}; !reflect.DeepEqual(sch.db.Todos["c"].BlockedBy, want) {
+		t.Errorf("c blocked by %v, want: %v", sch.db.Todos["c"].BlockedBy, want)
+	}
+	if len(sch.notices) != 0 {
+		t.Errorf("got notices %v while c is still blocked", sch.notices)
+	}
+	// Snoozed todo is removed without unblocking.
+	sch.deleteTodo("b")
+	if len(sch.db.Todos["c"].BlockedBy) != 1 {
+		t.Errorf("c unblocked by snooze")
+	}
+	sch.unblock("b")
+	if len(sch.db.Todos["c"].BlockedBy) != 0 {
+		t.Errorf("c still blocked by %v", sch.db.Todos["c"].BlockedBy)
+	}
+	if want := []string{"unblocked: c"}; !reflect.DeepEqual(sch.notices, want) {
+		t.Errorf("got notices %v, want: %v", sch.notices, want)
+	}
+}
+
+func TestSnoozedTodoKeepsID(t *testing.T) {
+	trigger := Trigger{Name: "b", Cron: onceCron, Count: 1, Subtasks: []string{"x", "y"}, Snoozed: []string{"b", "x"}}
+	todos := trigger.newTodos(time.Now())
+	if todos[0].ID != "b" || todos[1].ID != "x" || todos[2].ID == "" {
+		t.Errorf("got IDs %s, %s, %s, want: b, x and a new one", todos[0].ID, todos[1].ID, todos[2].ID)
+	}
+	if want := []string{"x", todos[2].ID}; !reflect.DeepEqual(todos[0].Subtasks, want) {
+		t.Errorf("got subtasks %v, want: %v", todos[0].Subtasks, want)
+	}
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -14,22 +16,136 @@ import (
 	cron "github.com/robfig/cron/v3"
 )
 
+// defaultList is the name of list used if no other is chosen.
+const defaultList = "default"
+
 // Todo represents something to accomplish.
 type Todo struct {
-	Name      string
-	ID        string
-	CreatedAt time.Time
+	Name        string
+	ID          string
+	ShortID     int // stable number shown as "#12".
+	List        string
+	Priority    int
+	Tags        []string
+	CreatedAt   time.Time
+	Due         time.Time
+	CompletedAt time.Time
+	Parent      string   // ID of todo this one is a subtask of.
+	Subtasks    []string // IDs of subtasks in the order they were defined.
+	BlockedBy   []string // IDs of todos to finish before this one.
+	Notify      bool     // whether to notify when todo gets unblocked.
+	TriggerID   string   // ID of trigger which created todo.
+	TriggerKind string   // kind of trigger which created todo, see triggerKind.
+	Repeat      int      // number of occurrences merged into todo, if more than one.
+	Intervals   []Interval
+	Pomodoros   int // number of completed pomodoros.
 }
 
 // Trigger defines when to create a Todo.
 type Trigger struct {
-	Name  string
-	Cron  string
-	After time.Time
-	Count int
-	ID    string
+	Name     string
+	Cron     string
+	After    time.Time
+	Count    int
+	ID       string
+	ShortID  int // stable number shown as "#12".
+	List     string
+	Priority int
+	Tags     []string
+	DueIn    time.Duration // deadline of created todos relative to their creation.
+	Subtasks []string      // names of subtasks created along with todo.
+	Paused   bool
+	ResumeAt time.Time // when paused trigger is resumed automatically.
+	Replay   bool      // whether to catch up occurrences missed while paused.
+	CatchUp  CatchUp
+	TZ       string // time zone of schedule, local one if empty.
+	// Every makes one-time trigger fire again this long after todo it created
+	// is done or removed.
+	Every      time.Duration
+	Source     string    // ID of trigger created todos are attributed to, if not this one.
+	SourceKind string    // kind of trigger of snoozed todo.
+	Snoozed    []string  // IDs of snoozed todo and its subtasks, kept by todos created again.
+	Until      time.Time // end of schedule (exclusive), if non-zero.
+	Overlap    Overlap
+}
+
+// CatchUp defines how trigger handles occurrences missed while termtodo
+// wasn't running.
+type CatchUp int
+
+const (
+	CatchUpOne  CatchUp = iota // create a single todo for all missed occurrences.
+	CatchUpAll                 // create todo for each missed occurrence.
+	CatchUpSkip                // don't create todos for missed occurrences.
+)
+
+// maxCatchUp limits number of todos created at once by CatchUpAll.
+const maxCatchUp = 100
+
+// catchUpGrace is how late occurrence can be checked without being missed.
+const catchUpGrace = time.Minute
+
+func (c CatchUp) String() string {
+	switch c {
+	case CatchUpAll:
+		return "all"
+	case CatchUpSkip:
+		return "skip"
+	default:
+		return "one"
+	}
 }
 
+// ParseCatchUp returns catch-up policy named s.
+func ParseCatchUp(s string) (CatchUp, error) {
+	switch s {
+	case "one":
+		return CatchUpOne, nil
+	case "all":
+		return CatchUpAll, nil
+	case "skip":
+		return CatchUpSkip, nil
+	}
+	return CatchUpOne, errors.New("invalid catch-up policy: " + s)
+}
+
+// Overlap defines what trigger does if todo it created previously is still
+// open.
+type Overlap int
+
+const (
+	OverlapNew  Overlap = iota // always create a new todo.
+	OverlapSkip                // don't create todo.
+	OverlapBump                // increase repeat counter of the open todo.
+)
+
+func (o Overlap) String() string {
+	switch o {
+	case OverlapSkip:
+		return "skip"
+	case OverlapBump:
+		return "bump"
+	default:
+		return "new"
+	}
+}
+
+// ParseOverlap returns overlap policy named s.
+func ParseOverlap(s string) (Overlap, error) {
+	switch s {
+	case "new":
+		return OverlapNew, nil
+	case "skip":
+		return OverlapSkip, nil
+	case "bump":
+		return OverlapBump, nil
+	}
+	return OverlapNew, errors.New("invalid overlap policy: " + s)
+}
+
+// onceCron is the schedule of one-time triggers which fire at After.
+const onceCron = "*/1 * * * * *"
+
 func NewTrigger(name, cron string, after time.Time, count int) (Trigger, error) {
 	t := Trigger{
 		Name:  name,
@@ -44,7 +160,11 @@ func NewTrigger(name, cron string, after time.Time, count int) (Trigger, error)
 
 func (t *Trigger) Schedule() (cron.Schedule, error) {
 	parser := cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
-	sched, err := parser.Parse(t.Cron)
+	spec := t.Cron
+	if t.TZ != "" {
+		spec = "CRON_TZ=" + t.TZ + " " + spec
+	}
+	sched, err := parser.Parse(spec)
 	if err != nil {
 		return nil, fmt.Errorf("invalid schedule: %w", err)
 	}
@@ -59,46 +179,371 @@ func (t *Trigger) Next() time.Time {
 	if err != nil {
 		panic(err)
 	}
-	return sch.Next(t.After)
+	next := sch.Next(t.After)
+	if t.ended(next) {
+		return time.Time{}
+	}
+	return next
+}
+
+// ended reports whether occurrence at n is past the end of schedule.
+func (t *Trigger) ended(n time.Time) bool {
+	return !t.Until.IsZero() && !n.Before(t.Until)
+}
+
+// Location returns time zone of trigger's schedule.
+func (t *Trigger) Location() *time.Location {
+	if t.TZ == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(t.TZ)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// SplitTZ separates time zone prefix ("CRON_TZ=" or "TZ=") from cron spec.
+func SplitTZ(spec string) (tz, cron string) {
+	for _, prefix := range []string{"CRON_TZ=", "TZ="} {
+		if strings.HasPrefix(spec, prefix) {
+			fields := strings.SplitN(spec[len(prefix):], " ", 2)
+			if len(fields) == 2 {
+				return fields[0], strings.TrimSpace(fields[1])
+			}
+		}
+	}
+	return "", spec
+}
+
+// OneTime reports whether trigger fires only once, at After.
+func (t *Trigger) OneTime() bool {
+	return t.Cron == onceCron && t.Count == 1
+}
+
+// Wakeup returns the time trigger needs to be checked at. Zero time means
+// that it doesn't have to be checked.
+func (t *Trigger) Wakeup() time.Time {
+	if t.Paused {
+		return t.ResumeAt
+	}
+	return t.Next()
 }
 
-func (t *Trigger) Check() *Todo {
-	now := time.Now()
-	if t.Count == 0 || t.Next().After(now) {
+// Pause stops trigger from creating todos. Non-zero until sets the time when
+// trigger is resumed automatically.
+func (t *Trigger) Pause(until time.Time, replay bool) {
+	t.Paused = true
+	t.ResumeAt = until
+	t.Replay = replay
+}
+
+// Resume undoes Pause. If replay is false, occurrences which were due while
+// paused are skipped. One-time trigger keeps its time, so it fires right
+// away if the time has passed.
+func (t *Trigger) Resume(now time.Time, replay bool) {
+	if !replay && !t.OneTime() {
+		t.After = now
+	}
+	t.Paused = false
+	t.ResumeAt = time.Time{}
+	t.Replay = false
+}
+
+// Check returns todos to create if trigger is due at now. Each created todo
+// is followed by its subtasks.
+func (t *Trigger) Check(now time.Time) []Todo {
+	if t.Paused && !t.ResumeAt.IsZero() && !t.ResumeAt.After(now) {
+		t.Resume(now, t.Replay)
+	}
+	next := t.Next()
+	// Zero next means trigger is exhausted or past its end.
+	if t.Paused || next.IsZero() || next.After(now) {
 		return nil
 	}
+	sch, err := t.Schedule()
+	if err != nil {
+		panic(err)
+	}
+	var times []time.Time
+	switch t.CatchUp {
+	case CatchUpAll:
+		for n := next; !n.After(now) && len(times) < maxCatchUp; n = sch.Next(n) {
+			if t.Count != -1 && len(times) == t.Count || t.ended(n) {
+				break
+			}
+			times = append(times, n)
+		}
+	case CatchUpSkip:
+		// Only the most recent occurrence can be on time.
+		last := sch.Next(now.Add(-catchUpGrace))
+		if t.OneTime() {
+			last = next
+		}
+		if last.After(now) || now.Sub(last) > catchUpGrace || t.ended(last) {
+			t.After = now
+			if t.OneTime() {
+				// Its only occurrence is missed.
+				t.Count = 0
+				if t.Every != 0 {
+					t.After, t.Count = now.Add(t.Every), 1
+				}
+			}
+			return nil
+		}
+		times = append(times, now)
+	default:
+		times = append(times, now)
+	}
 	t.After = now
-	if t.Count != -1 {
-		t.Count--
+	var todos []Todo
+	for _, createdAt := range times {
+		if t.Count != -1 {
+			t.Count--
+		}
+		todos = append(todos, t.newTodos(createdAt)...)
+	}
+	return todos
+}
+
+// newTodos returns todo created at the given time followed by its subtasks.
+func (t *Trigger) newTodos(createdAt time.Time) []Todo {
+	todo := Todo{
+		Name:        t.Name,
+		ID:          t.todoID(0),
+		List:        t.List,
+		Priority:    t.Priority,
+		Tags:        t.Tags,
+		CreatedAt:   createdAt,
+		TriggerID:   t.ID,
+		TriggerKind: triggerKind(*t),
+	}
+	if t.Source != "" {
+		todo.TriggerID = t.Source
+		todo.TriggerKind = t.SourceKind
+	}
+	if t.DueIn != 0 {
+		todo.Due = createdAt.Add(t.DueIn)
+	}
+	todos := []Todo{todo}
+	for i, name := range t.Subtasks {
+		sub := todo
+		sub.Name = name
+		sub.ID = t.todoID(i + 1)
+		sub.Parent = todo.ID
+		todos = append(todos, sub)
+		todos[0].Subtasks = append(todos[0].Subtasks, sub.ID)
+	}
+	return todos
+}
+
+// todoID returns ID for i-th todo created at once, subtasks following their
+// parent. Snoozed todos get their IDs back, so todos blocked by them stay
+// blocked.
+func (t *Trigger) todoID(i int) string {
+	if i < len(t.Snoozed) {
+		return t.Snoozed[i]
 	}
-	return &Todo{Name: t.Name, ID: uuid.New().String(), CreatedAt: time.Now()}
+	return uuid.New().String()
+}
+
+// SavedView is a filter query saved under a name. Empty query deletes view.
+type SavedView struct {
+	Name  string
+	Query string
+}
+
+// A TodoUpdate changes scheduler's copy of todo, so fields modified since
+// UI got the todo (e.g. Repeat) aren't overwritten.
+type TodoUpdate struct {
+	ID     string
+	Update func(todo *Todo)
+}
+
+// A TriggerUpdate changes scheduler's copy of trigger, like TodoUpdate.
+type TriggerUpdate struct {
+	ID     string
+	Update func(trigger *Trigger)
+}
+
+// Snooze replaces todos with triggers which create them again later.
+type Snooze struct {
+	Todos    []string
+	Triggers []Trigger
 }
 
 type Scheduler struct {
-	TodosCh       chan []Todo
-	TriggersCh    chan []Trigger
-	AddTriggersCh chan []Trigger
-	DelTriggersCh chan []string
-	DelTodosCh    chan []string
-	timer         *time.Timer
-	db            *DB
+	TodosCh          chan []Todo
+	TriggersCh       chan []Trigger
+	HistoryCh        chan []Todo
+	ErrCh            chan error
+	NoticeCh         chan string
+	ViewsCh          chan map[string]string
+	AddTriggersCh    chan []Trigger
+	DelTriggersCh    chan []string
+	DelTodosCh       chan []string
+	DoneTodosCh      chan []string
+	UpdateTodosCh    chan []TodoUpdate
+	UpdateTriggersCh chan []TriggerUpdate
+	SnoozeCh         chan Snooze
+	PomodoroDoneCh   chan string // ID of todo which got pomodoro done.
+	SaveViewCh       chan SavedView
+	UndoCh           chan struct{}
+	RedoCh           chan struct{}
+	timer            *time.Timer
+	db               *DB
+	notices          []string
 }
 
-func (sch *Scheduler) checkTriggers() {
+// checkTriggers creates todos of triggers due at now and removes exhausted
+// triggers.
+func (sch *Scheduler) checkTriggers(now time.Time) {
 	triggers := make(map[string]Trigger)
 	for _, trigger := range sch.db.Triggers {
-		if todo := trigger.Check(); todo != nil {
-			sch.db.Todos[(*todo).ID] = *todo
+		dropped := "" // ID of todo whose subtasks aren't created.
+		for _, todo := range trigger.Check(now) {
+			if todo.Parent == "" && trigger.Overlap != OverlapNew {
+				if open, ok := sch.openTodo(todo.TriggerID); ok {
+					if trigger.Overlap == OverlapBump {
+						if open.Repeat == 0 {
+							open.Repeat = 1
+						}
+						open.Repeat++
+						sch.db.Todos[open.ID] = open
+					}
+					dropped = todo.ID
+					continue
+				}
+			}
+			if todo.Parent != "" && todo.Parent == dropped {
+				continue
+			}
+			sch.db.Todos[todo.ID] = todo
 		}
-		if !trigger.Next().IsZero() {
+		// Recurring after completion trigger waits for todo it created.
+		waiting := trigger.Every != 0 && !trigger.ended(now)
+		if !trigger.Next().IsZero() || waiting {
 			triggers[trigger.ID] = trigger
 		}
 	}
 	sch.db.Triggers = triggers
-	err := sch.db.Write()
-	if err != nil {
-		panic(err)
+	sch.db.assignShortIDs()
+}
+
+// openTodo returns todo created by trigger which isn't done yet.
+func (sch *Scheduler) openTodo(triggerID string) (Todo, bool) {
+	if triggerID == "" {
+		return Todo{}, false
+	}
+	for _, todo := range sch.db.Todos {
+		if todo.TriggerID == triggerID && todo.Parent == "" {
+			return todo, true
+		}
+	}
+	return Todo{}, false
+}
+
+// rearm schedules the next todo of trigger recurring after completion once
+// todo it created is done or removed.
+func (sch *Scheduler) rearm(todo Todo, now time.Time) {
+	trigger, ok := sch.db.Triggers[todo.TriggerID]
+	if !ok || trigger.Every == 0 || trigger.Count != 0 || todo.Parent != "" {
+		return
+	}
+	trigger.After = now.Add(trigger.Every)
+	trigger.Count = 1
+	if trigger.Next().IsZero() {
+		delete(sch.db.Triggers, trigger.ID)
+		return
+	}
+	sch.db.Triggers[trigger.ID] = trigger
+}
+
+// unblock removes todo id from blockers of other todos.
+func (sch *Scheduler) unblock(id string) {
+	for _, todo := range sch.db.Todos {
+		blockedBy := make([]string, 0, len(todo.BlockedBy))
+		for _, b := range todo.BlockedBy {
+			if b != id {
+				blockedBy = append(blockedBy, b)
+			}
+		}
+		if len(blockedBy) == len(todo.BlockedBy) {
+			continue
+		}
+		todo.BlockedBy = blockedBy
+		sch.db.Todos[todo.ID] = todo
+		if len(blockedBy) == 0 && todo.Notify {
+			sch.notices = append(sch.notices, "unblocked: "+todo.Name)
+		}
+	}
+}
+
+// deleteTodo removes todo together with its subtasks. Todos blocked by
+// them stay blocked, see unblock.
+func (sch *Scheduler) deleteTodo(id string) {
+	todo, ok := sch.db.Todos[id]
+	if !ok {
+		return
 	}
+	delete(sch.db.Todos, id)
+	for _, sub := range todo.Subtasks {
+		delete(sch.db.Todos, sub)
+	}
+	if parent, ok := sch.db.Todos[todo.Parent]; ok {
+		subtasks := make([]string, 0, len(parent.Subtasks))
+		for _, sub := range parent.Subtasks {
+			if sub != id {
+				subtasks = append(subtasks, sub)
+			}
+		}
+		parent.Subtasks = subtasks
+		sch.db.Todos[parent.ID] = parent
+	}
+}
+
+// removeTodo deletes todo together with its subtasks as removed by user, so
+// todos blocked by them get unblocked and trigger recurring after completion
+// schedules the next todo.
+func (sch *Scheduler) removeTodo(id string, now time.Time) {
+	todo, ok := sch.db.Todos[id]
+	if !ok {
+		return
+	}
+	sch.deleteTodo(id)
+	sch.unblock(id)
+	for _, sub := range todo.Subtasks {
+		sch.unblock(sub)
+	}
+	sch.rearm(todo, now)
+}
+
+// completeTodo moves todo together with its subtasks to the history. Parent
+// todo is completed as well once all its subtasks are done.
+func (sch *Scheduler) completeTodo(id string, now time.Time) {
+	todo, ok := sch.db.Todos[id]
+	if !ok {
+		return
+	}
+	todo.CompletedAt = now
+	todo.StopTracking(now)
+	sch.db.History[id] = todo
+	delete(sch.db.Todos, id)
+	sch.unblock(id)
+	sch.rearm(todo, now)
+	for _, sub := range todo.Subtasks {
+		sch.completeTodo(sub, now)
+	}
+	parent, ok := sch.db.Todos[todo.Parent]
+	if !ok {
+		return
+	}
+	for _, sub := range parent.Subtasks {
+		if _, ok := sch.db.Todos[sub]; ok {
+			return
+		}
+	}
+	sch.completeTodo(parent.ID, now)
 }
 
 func (sch *Scheduler) sendTodos() {
@@ -117,61 +562,163 @@ func (sch *Scheduler) sendTriggers() {
 	sch.TriggersCh <- triggers
 }
 
+func (sch *Scheduler) sendHistory() {
+	history := make([]Todo, 0, len(sch.db.History))
+	for _, todo := range sch.db.History {
+		history = append(history, todo)
+	}
+	sch.HistoryCh <- history
+}
+
+func (sch *Scheduler) sendViews() {
+	views := make(map[string]string, len(sch.db.Views))
+	for name, query := range sch.db.Views {
+		views[name] = query
+	}
+	sch.ViewsCh <- views
+}
+
 func NewScheduler(db *DB) *Scheduler {
 	sch := Scheduler{
-		TodosCh:       make(chan []Todo),
-		TriggersCh:    make(chan []Trigger),
-		AddTriggersCh: make(chan []Trigger),
-		DelTriggersCh: make(chan []string),
-		DelTodosCh:    make(chan []string),
-		timer:         time.NewTimer(time.Millisecond),
-		db:            db,
+		TodosCh:          make(chan []Todo),
+		TriggersCh:       make(chan []Trigger),
+		HistoryCh:        make(chan []Todo),
+		ErrCh:            make(chan error),
+		NoticeCh:         make(chan string),
+		ViewsCh:          make(chan map[string]string),
+		AddTriggersCh:    make(chan []Trigger),
+		DelTriggersCh:    make(chan []string),
+		DelTodosCh:       make(chan []string),
+		DoneTodosCh:      make(chan []string),
+		UpdateTodosCh:    make(chan []TodoUpdate),
+		UpdateTriggersCh: make(chan []TriggerUpdate),
+		SnoozeCh:         make(chan Snooze),
+		PomodoroDoneCh:   make(chan string),
+		SaveViewCh:       make(chan SavedView),
+		UndoCh:           make(chan struct{}),
+		RedoCh:           make(chan struct{}),
+		timer:            time.NewTimer(time.Millisecond),
+		db:               db,
 	}
 	go func() {
 		sch.sendTodos()
 		sch.sendTriggers()
+		sch.sendHistory()
+		sch.sendViews()
 		for {
 			timerExpired := false
-			triggersNum := len(db.Triggers)
-			todosNum := len(db.Todos)
+			viewsChanged := false
+			before := db.state()
 			select {
 			case ids := <-sch.DelTodosCh:
-				for _, id := range ids {
-					delete(db.Todos, id)
-				}
-				err := sch.db.Write()
-				if err != nil {
-					panic(err)
-				}
+				db.record(func() {
+					now := time.Now()
+					for _, id := range ids {
+						sch.removeTodo(id, now)
+					}
+				})
+			case ids := <-sch.DoneTodosCh:
+				db.record(func() {
+					now := time.Now()
+					for _, id := range ids {
+						sch.completeTodo(id, now)
+					}
+				})
 			case triggers := <-sch.AddTriggersCh:
-				for _, trigger := range triggers {
-
-					db.Triggers[trigger.ID] = trigger
-				}
-				sch.checkTriggers()
+				db.record(func() {
+					for _, trigger := range triggers {
+						db.Triggers[trigger.ID] = trigger
+					}
+					db.assignShortIDs()
+				})
+				sch.checkTriggers(time.Now())
 			case ids := <-sch.DelTriggersCh:
-				for _, id := range ids {
-					delete(db.Triggers, id)
+				db.record(func() {
+					for _, id := range ids {
+						delete(db.Triggers, id)
+					}
+				})
+			case updates := <-sch.UpdateTodosCh:
+				db.record(func() {
+					for _, u := range updates {
+						if todo, ok := db.Todos[u.ID]; ok {
+							u.Update(&todo)
+							db.Todos[u.ID] = todo
+						}
+					}
+				})
+			case id := <-sch.PomodoroDoneCh:
+				// Not recorded, so finished pomodoro isn't undone.
+				if todo, ok := db.Todos[id]; ok {
+					todo.Pomodoros++
+					db.Todos[id] = todo
 				}
-				err := sch.db.Write()
-				if err != nil {
-					panic(err)
+			case updates := <-sch.UpdateTriggersCh:
+				db.record(func() {
+					for _, u := range updates {
+						if trigger, ok := db.Triggers[u.ID]; ok {
+							u.Update(&trigger)
+							db.Triggers[u.ID] = trigger
+						}
+					}
+				})
+				sch.checkTriggers(time.Now())
+			case snooze := <-sch.SnoozeCh:
+				db.record(func() {
+					for _, id := range snooze.Todos {
+						sch.deleteTodo(id)
+					}
+					for _, trigger := range snooze.Triggers {
+						db.Triggers[trigger.ID] = trigger
+					}
+					db.assignShortIDs()
+				})
+				sch.checkTriggers(time.Now())
+			case view := <-sch.SaveViewCh:
+				if view.Query == "" {
+					delete(db.Views, view.Name)
+				} else {
+					db.Views[view.Name] = view.Query
+				}
+				viewsChanged = true
+			case <-sch.UndoCh:
+				if err := db.undo(); err != nil {
+					sch.ErrCh <- err
+				}
+			case <-sch.RedoCh:
+				if err := db.redo(); err != nil {
+					sch.ErrCh <- err
 				}
 			case <-sch.timer.C:
-				sch.checkTriggers()
+				sch.checkTriggers(time.Now())
 				timerExpired = true
 			}
 
-			if len(db.Todos) != todosNum {
+			err := sch.db.Write()
+			if err != nil {
+				panic(err)
+			}
+			for _, notice := range sch.notices {
+				sch.NoticeCh <- notice
+			}
+			sch.notices = nil
+			change := diff(before, db.state())
+			if len(change.After.Todos) > 0 {
 				sch.sendTodos()
 			}
-			if len(db.Triggers) != triggersNum {
+			if len(change.After.Triggers) > 0 {
 				sch.sendTriggers()
 			}
+			if len(change.After.History) > 0 {
+				sch.sendHistory()
+			}
+			if viewsChanged {
+				sch.sendViews()
+			}
 
 			nextCheck := time.Now().Add(time.Hour * 24 * 7)
 			for _, trigger := range sch.db.Triggers {
-				n := trigger.Next()
+				n := trigger.Wakeup()
 				if !n.IsZero() && n.Before(nextCheck) {
 					nextCheck = n
 				}
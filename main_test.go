@@ -0,0 +1,260 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTriggerCheck(t *testing.T) {
+	day := func(d, h, m, s int) time.Time { return time.Date(2026, 3, d, h, m, s, 0, time.Local) }
+	tests := []struct {
+		name      string
+		cron      string
+		after     time.Time
+		count     int
+		catchUp   CatchUp
+		every     time.Duration
+		now       time.Time
+		wantTodos int
+		wantCount int
+		wantAfter time.Time
+	}{
+		{"not due", "0 9 * * *", day(1, 8, 0, 0), -1, CatchUpOne, 0, day(1, 8, 30, 0), 0, -1, day(1, 8, 0, 0)},
+		{"one", "0 9 * * *", day(1, 8, 0, 0), -1, CatchUpOne, 0, day(3, 12, 0, 0), 1, -1, day(3, 12, 0, 0)},
+		{"one with count", "0 9 * * *", day(1, 8, 0, 0), 5, CatchUpOne, 0, day(3, 12, 0, 0), 1, 4, day(3, 12, 0, 0)},
+		{"all", "0 9 * * *", day(1, 8, 0, 0), -1, CatchUpAll, 0, day(3, 12, 0, 0), 3, -1, day(3, 12, 0, 0)},
+		{"all limited by count", "0 9 * * *", day(1, 8, 0, 0), 2, CatchUpAll, 0, day(3, 12, 0, 0), 2, 0, day(3, 12, 0, 0)},
+		{"skip missed", "0 9 * * *", day(1, 8, 0, 0), -1, CatchUpSkip, 0, day(3, 12, 0, 0), 0, -1, day(3, 12, 0, 0)},
+		{"skip on time", "0 9 * * *", day(1, 8, 0, 0), -1, CatchUpSkip, 0, day(3, 9, 0, 30), 1, -1, day(3, 9, 0, 30)},
+		{"skip missed one-time", onceCron, day(1, 8, 0, 0), 1, CatchUpSkip, 0, day(3, 12, 0, 0), 0, 0, day(3, 12, 0, 0)},
+		{"skip one-time on time", onceCron, day(1, 8, 0, 0), 1, CatchUpSkip, 0, day(1, 8, 0, 30), 1, 0, day(1, 8, 0, 30)},
+		{"skip missed every", onceCron, day(1, 8, 0, 0), 1, CatchUpSkip, 24 * time.Hour, day(3, 12, 0, 0), 0, 1, day(4, 12, 0, 0)},
+		{"one-time", onceCron, day(1, 8, 0, 0), 1, CatchUpOne, 0, day(3, 12, 0, 0), 1, 0, day(3, 12, 0, 0)},
+	}
+
+	for _, test := range tests {
+		trigger, err := NewTrigger(test.name, test.cron, test.after, test.count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trigger.CatchUp = test.catchUp
+		trigger.Every = test.every
+		todos := trigger.Check(test.now)
+		if len(todos) != test.wantTodos {
+			t.Errorf("%s: got %d todos, want: %d", test.name, len(todos), test.wantTodos)
+		}
+		if trigger.Count != test.wantCount {
+			t.Errorf("%s: count = %d, want: %d", test.name, trigger.Count, test.wantCount)
+		}
+		if !trigger.After.Equal(test.wantAfter) {
+			t.Errorf("%s: after = %v, want: %v", test.name, trigger.After, test.wantAfter)
+		}
+		// Trigger doesn't fire again right after the check.
+		if todos := trigger.Check(test.now.Add(1100 * time.Millisecond)); len(todos) != 0 {
+			t.Errorf("%s: got %d todos on the next check", test.name, len(todos))
+		}
+	}
+}
+
+func TestNewTodosKind(t *testing.T) {
+	now := time.Date(2026, 3, 1, 8, 0, 0, 0, time.Local)
+	once, err := NewTrigger("tea", onceCron, now, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	once.Subtasks = []string{"kettle"}
+	for _, todo := range once.newTodos(now) {
+		if todo.TriggerKind != "once" {
+			t.Errorf("%s: kind = %q, want: once", todo.Name, todo.TriggerKind)
+		}
+	}
+	// Snoozed todo keeps kind of the trigger which created it.
+	once.Source, once.SourceKind = "cron-id", "cron"
+	todo := once.newTodos(now)[0]
+	if todo.TriggerID != "cron-id" || todo.TriggerKind != "cron" {
+		t.Errorf("snoozed todo: trigger %q, kind %q, want: cron-id, cron", todo.TriggerID, todo.TriggerKind)
+	}
+}
+
+func TestTriggerResume(t *testing.T) {
+	now := time.Date(2026, 3, 1, 8, 0, 0, 0, time.Local)
+	for _, replay := range []bool{false, true} {
+		trigger, err := NewTrigger("tea", onceCron, now.Add(24*time.Hour), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		next := trigger.Next()
+		trigger.Pause(time.Time{}, replay)
+		trigger.Resume(now.Add(time.Hour), replay)
+		if !trigger.Next().Equal(next) {
+			t.Errorf("replay %v: next = %v after resume, want: %v", replay, trigger.Next(), next)
+		}
+		// Resumed automatically.
+		trigger.Pause(now.Add(2*time.Hour), replay)
+		if todos := trigger.Check(now.Add(3 * time.Hour)); len(todos) != 0 || trigger.Paused {
+			t.Errorf("replay %v: got %d todos, paused %v after auto-resume", replay, len(todos), trigger.Paused)
+		}
+		if !trigger.Next().Equal(next) {
+			t.Errorf("replay %v: next = %v after auto-resume, want: %v", replay, trigger.Next(), next)
+		}
+	}
+}
+
+func TestRearm(t *testing.T) {
+	day := func(d, h, m, s int) time.Time { return time.Date(2026, 3, d, h, m, s, 0, time.Local) }
+	tests := []struct {
+		name        string
+		until       time.Time
+		action      string // done or rm.
+		subtask     bool   // action applies to the first subtask.
+		wantTrigger bool
+		wantCount   int
+		wantAfter   time.Time
+	}{
+		{"done", time.Time{}, "done", false, true, 1, day(2, 10, 0, 0)},
+		{"rm", time.Time{}, "rm", false, true, 1, day(2, 10, 0, 0)},
+		{"done subtask", time.Time{}, "done", true, true, 0, day(1, 9, 0, 0)},
+		{"rm subtask", time.Time{}, "rm", true, true, 0, day(1, 9, 0, 0)},
+		{"done past until", day(2, 0, 0, 0), "done", false, false, 0, time.Time{}},
+	}
+
+	for _, test := range tests {
+		sch := &Scheduler{db: newTestDB()}
+		trigger, err := NewTrigger(test.name, onceCron, day(1, 8, 0, 0), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trigger.Every = 24 * time.Hour
+		trigger.Until = test.until
+		trigger.Subtasks = []string{"kettle", "cups"}
+		sch.db.Triggers[trigger.ID] = trigger
+		sch.checkTriggers(day(1, 9, 0, 0))
+		if got := sch.db.Triggers[trigger.ID]; got.Count != 0 {
+			t.Fatalf("%s: trigger doesn't wait for todo, count = %d", test.name, got.Count)
+		}
+		todo, ok := sch.openTodo(trigger.ID)
+		if !ok {
+			t.Fatalf("%s: todo not created", test.name)
+		}
+		id := todo.ID
+		if test.subtask {
+			id = todo.Subtasks[0]
+		}
+		now := day(1, 10, 0, 0)
+		switch test.action {
+		case "done":
+			sch.completeTodo(id, now)
+		case "rm":
+			sch.removeTodo(id, now)
+		}
+
+		got, ok := sch.db.Triggers[trigger.ID]
+		if ok != test.wantTrigger {
+			t.Errorf("%s: trigger kept = %v, want: %v", test.name, ok, test.wantTrigger)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if got.Count != test.wantCount || !got.After.Equal(test.wantAfter) {
+			t.Errorf("%s: count %d, after %v, want: %d, %v", test.name, got.Count, got.After, test.wantCount, test.wantAfter)
+		}
+		if test.wantCount == 0 {
+			continue
+		}
+		// The next todo is created once the interval has passed.
+		sch.checkTriggers(test.wantAfter.Add(time.Second))
+		if _, ok := sch.openTodo(trigger.ID); !ok {
+			t.Errorf("%s: next todo not created", test.name)
+		}
+	}
+}
+
+func TestTriggerCountAndUntil(t *testing.T) {
+	day := func(d, h, m, s int) time.Time { return time.Date(2026, 3, d, h, m, s, 0, time.Local) }
+	tests := []struct {
+		name  string
+		count int
+		until time.Time
+		// checks are followed by the number of todos and whether trigger
+		// is kept after each of them.
+		checks      []time.Time
+		wantTodos   []int
+		wantTrigger []bool
+	}{
+		{"x2", 2, time.Time{}, []time.Time{day(1, 10, 0, 0), day(2, 10, 0, 0)}, []int{1, 2}, []bool{true, false}},
+		{"x2 caught up", 2, time.Time{}, []time.Time{day(3, 10, 0, 0)}, []int{1}, []bool{true}},
+		// Until is exclusive, so occurrence at it isn't created.
+		{"until occurrence", -1, day(2, 9, 0, 0), []time.Time{day(1, 10, 0, 0), day(2, 10, 0, 0)}, []int{1, 1}, []bool{false, false}},
+		{"until after occurrence", -1, day(2, 9, 0, 1), []time.Time{day(1, 10, 0, 0), day(2, 10, 0, 0)}, []int{1, 2}, []bool{true, false}},
+		{"until before first", -1, day(1, 8, 30, 0), []time.Time{day(1, 10, 0, 0)}, []int{0}, []bool{false}},
+	}
+
+	for _, test := range tests {
+		sch := &Scheduler{db: newTestDB()}
+		trigger, err := NewTrigger(test.name, "0 9 * * *", day(1, 8, 0, 0), test.count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trigger.Until = test.until
+		sch.db.Triggers[trigger.ID] = trigger
+		for i, now := range test.checks {
+			sch.checkTriggers(now)
+			if len(sch.db.Todos) != test.wantTodos[i] {
+				t.Errorf("%s: got %d todos after check at %v, want: %d", test.name, len(sch.db.Todos), now, test.wantTodos[i])
+			}
+			if _, ok := sch.db.Triggers[trigger.ID]; ok != test.wantTrigger[i] {
+				t.Errorf("%s: trigger kept = %v after check at %v, want: %v", test.name, ok, now, test.wantTrigger[i])
+			}
+		}
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	day := func(d, h int) time.Time { return time.Date(2026, 3, d, h, 0, 0, 0, time.Local) }
+	tests := []struct {
+		overlap    Overlap
+		done       bool // whether the first todo is done before it fires again.
+		wantTodos  int
+		wantRepeat int // of the open todo.
+	}{
+		{OverlapNew, false, 6, 0},
+		{OverlapSkip, false, 2, 0},
+		{OverlapBump, false, 2, 3},
+		{OverlapSkip, true, 2, 0},
+		{OverlapBump, true, 2, 2},
+	}
+
+	for _, test := range tests {
+		name := test.overlap.String()
+		sch := &Scheduler{db: newTestDB()}
+		trigger, err := NewTrigger(name, "0 9 * * *", day(1, 8), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		trigger.Overlap = test.overlap
+		trigger.Subtasks = []string{"notes"}
+		sch.db.Triggers[trigger.ID] = trigger
+		sch.checkTriggers(day(1, 10))
+		if test.done {
+			first, _ := sch.openTodo(trigger.ID)
+			sch.completeTodo(first.ID, day(1, 11))
+		}
+		sch.checkTriggers(day(2, 10))
+		sch.checkTriggers(day(3, 10))
+
+		if len(sch.db.Todos) != test.wantTodos {
+			t.Errorf("%s (done %v): got %d todos, want: %d", name, test.done, len(sch.db.Todos), test.wantTodos)
+		}
+		// Subtasks of skipped todo aren't created.
+		for _, todo := range sch.db.Todos {
+			if _, ok := sch.db.Todos[todo.Parent]; todo.Parent != "" && !ok {
+				t.Errorf("%s (done %v): subtask %s without parent", name, test.done, todo.Name)
+			}
+		}
+		open, _ := sch.openTodo(trigger.ID)
+		if open.Repeat != test.wantRepeat {
+			t.Errorf("%s (done %v): repeat = %d, want: %d", name, test.done, open.Repeat, test.wantRepeat)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+package main
+
+// Pattern defines how Blinkt! lights up.
+type Pattern int
+
+const (
+	PatternNone   Pattern = iota // all pixels off.
+	PatternAlert                 // single red pixel moving back and forth.
+	PatternUrgent                // all pixels flashing red.
+	PatternWork                  // all pixels lit blue during pomodoro.
+	PatternBreak                 // all pixels lit green during pomodoro break.
+)
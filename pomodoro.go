@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Default lengths of pomodoro work and break periods.
+const (
+	defaultPomoWork  = 25 * time.Minute
+	defaultPomoBreak = 5 * time.Minute
+)
+
+// pomodoro is a cycle of work and break periods spent on todo.
+type pomodoro struct {
+	todoID  string
+	work    time.Duration
+	rest    time.Duration
+	working bool
+	end     time.Time // end of the current period.
+}
+
+// next returns pomodoro switched to the following period.
+func (p pomodoro) next(now time.Time) *pomodoro {
+	p.working = !p.working
+	if p.working {
+		p.end = now.Add(p.work)
+	} else {
+		p.end = now.Add(p.rest)
+	}
+	return &p
+}
+
+// parsePomoLengths parses lengths of work and break periods like "25m/5m".
+func parsePomoLengths(s string) (work, rest time.Duration, err error) {
+	fields := strings.Split(s, "/")
+	if len(fields) != 2 {
+		return 0, 0, errors.New("invalid pomodoro: " + s)
+	}
+	if work, err = parseInterval(fields[0]); err != nil {
+		return 0, 0, err
+	}
+	if rest, err = parseInterval(fields[1]); err != nil {
+		return 0, 0, err
+	}
+	return work, rest, nil
+}
+
+// status returns description of the current period with time left.
+func (p *pomodoro) status(name string, now time.Time) string {
+	period := "break"
+	if p.working {
+		period = "work"
+	}
+	return fmt.Sprintf("%s %s: %s", period, formatElapsed(p.end.Sub(now)), name)
+}
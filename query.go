@@ -0,0 +1,253 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// A subject holds properties of todo or trigger which queries are evaluated
+// against.
+type subject struct {
+	now       time.Time
+	tags      []string
+	priority  int
+	createdAt time.Time // todos only.
+	due       time.Time // todos only.
+	blocked   bool      // todos only.
+	next      time.Time // triggers only.
+	paused    bool      // triggers only.
+	kind      string    // kind of trigger, for todos the one which created it.
+}
+
+// triggerKind returns "once", "every" or "cron" depending on how trigger
+// is scheduled.
+func triggerKind(t Trigger) string {
+	switch {
+	case t.Every != 0:
+		return "every"
+	case t.Cron == onceCron:
+		return "once"
+	default:
+		return "cron"
+	}
+}
+
+// A Query tells whether todo or trigger is wanted.
+type Query func(s *subject) bool
+
+// parseQuery parses query like "tag:work and age>2d and not blocked". Terms
+// next to each other are joined with "and". Supported terms:
+//
+//	tag:work, +work    has tag
+//	prio:1             has priority, prio:0 means no priority
+//	age>2d, age<1h     todo created more or less than given time ago
+//	due<1d, due>1w     todo due in less or more than given time
+//	next<1h, next>1d   trigger fires in less or more than given time
+//	overdue            todo is past its due time
+//	blocked            todo is blocked by another one
+//	paused             trigger is paused
+//	trigger:cron       trigger (or trigger of todo) is cron, once or every
+//
+// Terms can be combined with "and", "or", "not" and parentheses.
+func parseQuery(input string) (Query, error) {
+	p := queryParser{tokens: tokenizeQuery(input)}
+	if len(p.tokens) == 0 {
+		return nil, errors.New("empty query")
+	}
+	q, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok != "" {
+		return nil, fmt.Errorf("invalid query: unexpected %q", tok)
+	}
+	return q, nil
+}
+
+// tokenizeQuery splits query into words and parentheses.
+func tokenizeQuery(input string) []string {
+	var tokens []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range input {
+		switch {
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			word.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+type queryParser struct {
+	tokens []string
+}
+
+// peek returns the next token without consuming it. Empty string is returned
+// if there are no tokens left.
+func (p *queryParser) peek() string {
+	if len(p.tokens) == 0 {
+		return ""
+	}
+	return p.tokens[0]
+}
+
+func (p *queryParser) next() string {
+	tok := p.peek()
+	if tok != "" {
+		p.tokens = p.tokens[1:]
+	}
+	return tok
+}
+
+func (p *queryParser) parseOr() (Query, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "or" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(s *subject) bool { return l(s) || right(s) }
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (Query, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case "", "or", ")":
+			return left, nil
+		case "and":
+			p.next()
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(s *subject) bool { return l(s) && right(s) }
+	}
+}
+
+func (p *queryParser) parseNot() (Query, error) {
+	switch p.peek() {
+	case "not":
+		p.next()
+		q, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return func(s *subject) bool { return !q(s) }, nil
+	case "(":
+		p.next()
+		q, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, errors.New("invalid query: missing )")
+		}
+		return q, nil
+	}
+	return p.parseTerm()
+}
+
+func (p *queryParser) parseTerm() (Query, error) {
+	tok := p.next()
+	switch {
+	case tok == "":
+		return nil, errors.New("invalid query: unexpected end of query")
+	case tok == "overdue":
+		return func(s *subject) bool { return !s.due.IsZero() && s.due.Before(s.now) }, nil
+	case tok == "blocked":
+		return func(s *subject) bool { return s.blocked }, nil
+	case tok == "paused":
+		return func(s *subject) bool { return s.paused }, nil
+	case isTag(tok):
+		return tagQuery(tok[1:]), nil
+	case strings.HasPrefix(tok, "tag:") && len(tok) > len("tag:"):
+		return tagQuery(tok[len("tag:"):]), nil
+	case strings.HasPrefix(tok, "prio:"):
+		priority, err := parsePriority(tok[len("prio:"):])
+		if err != nil {
+			return nil, err
+		}
+		return func(s *subject) bool { return s.priority == priority }, nil
+	case strings.HasPrefix(tok, "trigger:"):
+		kind := tok[len("trigger:"):]
+		if kind != "cron" && kind != "once" && kind != "every" {
+			return nil, fmt.Errorf("invalid query: unknown trigger kind %q", kind)
+		}
+		return func(s *subject) bool { return s.kind == kind }, nil
+	}
+	for _, field := range []string{"age", "due", "next"} {
+		if !strings.HasPrefix(tok, field) || len(tok) < len(field)+2 {
+			continue
+		}
+		op := tok[len(field)]
+		if op != '<' && op != '>' {
+			continue
+		}
+		d, err := parseInterval(tok[len(field)+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid query: %w", err)
+		}
+		return compareQuery(field, op == '<', d), nil
+	}
+	return nil, fmt.Errorf("invalid query: unknown term %q", tok)
+}
+
+func tagQuery(tag string) Query {
+	return func(s *subject) bool { return hasTags(s.tags, []string{tag}) }
+}
+
+// compareQuery returns query comparing time from now till field (or since
+// field for age) with d. Subjects without the field don't match.
+func compareQuery(field string, less bool, d time.Duration) Query {
+	return func(s *subject) bool {
+		var diff time.Duration
+		switch field {
+		case "age":
+			if s.createdAt.IsZero() {
+				return false
+			}
+			diff = s.now.Sub(s.createdAt)
+		case "due":
+			if s.due.IsZero() {
+				return false
+			}
+			diff = s.due.Sub(s.now)
+		case "next":
+			if s.next.IsZero() {
+				return false
+			}
+			diff = s.next.Sub(s.now)
+		}
+		if less {
+			return diff < d
+		}
+		return diff > d
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	now := time.Date(2020, 5, 10, 12, 0, 0, 0, time.UTC)
+	stale := &subject{
+		now:       now,
+		tags:      []string{"work"},
+		priority:  1,
+		createdAt: now.Add(-72 * time.Hour),
+		due:       now.Add(-time.Hour),
+		kind:      "cron",
+	}
+	fresh := &subject{
+		now:       now,
+		tags:      []string{"home"},
+		createdAt: now.Add(-time.Hour),
+		blocked:   true,
+	}
+	soon := &subject{
+		now:  now,
+		tags: []string{"work"},
+		next: now.Add(30 * time.Minute),
+		kind: "cron",
+	}
+	subjects := []*subject{stale, fresh, soon}
+	tests := []struct {
+		query string
+		want  []bool
+	}{
+		{"+work", []bool{true, false, true}},
+		{"tag:home", []bool{false, true, false}},
+		{"prio:1", []bool{true, false, false}},
+		{"prio:0", []bool{false, true, true}},
+		{"age>2d", []bool{true, false, false}},
+		{"age<2d", []bool{false, true, false}},
+		{"overdue", []bool{true, false, false}},
+		{"due<1d", []bool{true, false, false}},
+		{"not blocked", []bool{true, false, true}},
+		{"trigger:cron next<1h", []bool{false, false, true}},
+		{"tag:work and age>2d and not blocked", []bool{true, false, false}},
+		{"+home or next<1h", []bool{false, true, true}},
+		{"not (+work or blocked)", []bool{false, false, false}},
+		{"(+home or +work) and not overdue", []bool{false, true, true}},
+	}
+
+	for _, test := range tests {
+		q, err := parseQuery(test.query)
+		if err != nil {
+			t.Errorf("parseQuery(%q) failed: %v", test.query, err)
+			continue
+		}
+		for i, s := range subjects {
+			if got := q(s); got != test.want[i] {
+				t.Errorf("parseQuery(%q) on subject %d = %v, want: %v", test.query, i, got, test.want[i])
+			}
+		}
+	}
+}
+
+func TestParseQueryError(t *testing.T) {
+	tests := []string{
+		"",
+		"foo",
+		"+work and",
+		"(+work",
+		"+work)",
+		"not",
+		"age>x",
+		"trigger:weekly",
+		"prio:x",
+	}
+
+	for _, query := range tests {
+		if _, err := parseQuery(query); err == nil {
+			t.Errorf("parseQuery(%q) succeeded, want error", query)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var errNothingSelected = errors.New("only excluded items given, use e.g. *,^4")
+
+// A selectable is an item which can be chosen by selector.
+type selectable struct {
+	id   int // short ID.
+	name string
+}
+
+// parseSelector returns indexes of items chosen by selector. Items are
+// given in the order they're listed. Selector is a comma-separated list of
+// terms:
+//
+//	3       the third item
+//	*       all items
+//	$       the last item
+//	-2      the second item from the end
+//	1-3     items from the first to the third one
+//	#12     item with short ID 12
+//	/re/    items with name matching regular expression
+//	~text   items with name containing letters of text in the same order
+//	^4      excludes the fourth item (or any other term)
+//
+// Indexes are returned in ascending order.
+func parseSelector(selector string, items []selectable) ([]int, error) {
+	included := make(map[int]bool)
+	excluded := make(map[int]bool)
+	for _, term := range splitTerms(selector) {
+		set := included
+		if strings.HasPrefix(term, "^") {
+			set = excluded
+			term = term[1:]
+		}
+		idxs, err := parseTerm(term, items)
+		if err != nil {
+			return nil, err
+		}
+		for _, idx := range idxs {
+			set[idx] = true
+		}
+	}
+	if len(included) == 0 && len(excluded) > 0 {
+		return nil, errNothingSelected
+	}
+	idxs := make([]int, 0, len(included))
+	for idx := range included {
+		if !excluded[idx] {
+			idxs = append(idxs, idx)
+		}
+	}
+	sort.Ints(idxs)
+	return idxs, nil
+}
+
+// splitTerms splits selector into terms. Commas inside regular expressions
+// don't separate terms.
+func splitTerms(selector string) []string {
+	var terms []string
+	start := 0
+	inRegexp := false
+	for i := 0; i < len(selector); i++ {
+		switch selector[i] {
+		case '/':
+			term := strings.TrimPrefix(selector[start:i], "^")
+			if term == "" || inRegexp {
+				inRegexp = !inRegexp
+			}
+		case '\\':
+			if inRegexp {
+				i++
+			}
+		case ',':
+			if !inRegexp {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, selector[start:])
+}
+
+// hasText reports whether selector chooses items by their names.
+func hasText(selector string) bool {
+	for _, term := range splitTerms(selector) {
+		if strings.HasPrefix(term, "/") || strings.HasPrefix(term, "~") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTerm returns indexes of items chosen by a single selector term.
+func parseTerm(term string, items []selectable) ([]int, error) {
+	switch {
+	case term == "":
+		return nil, errors.New("empty selector")
+	case term == "*":
+		if len(items) == 0 {
+			return nil, nil
+		}
+		return seq(0, len(items)-1), nil
+	case strings.HasPrefix(term, "#"):
+		id, err := strconv.Atoi(term[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid ID: %q", term)
+		}
+		for i, item := range items {
+			if item.id == id {
+				return []int{i}, nil
+			}
+		}
+		return nil, errors.New("no item with ID " + term)
+	case strings.HasPrefix(term, "/"):
+		if len(term) < 2 || !strings.HasSuffix(term, "/") {
+			return nil, fmt.Errorf("unterminated regular expression: %q", term)
+		}
+		re, err := regexp.Compile(term[1 : len(term)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		return matching(term, items, re.MatchString)
+	case strings.HasPrefix(term, "~"):
+		if len(term) == 1 {
+			return nil, fmt.Errorf("empty text: %q", term)
+		}
+		return matching(term, items, func(name string) bool { return fuzzyMatch(term[1:], name) })
+	}
+	n := len(items)
+	// Dash at the beginning is a sign of position counted from the end.
+	if sep := strings.Index(term[1:], "-"); sep != -1 {
+		from, err := parsePosition(term[:sep+1], n)
+		if err != nil {
+			return nil, err
+		}
+		to, err := parsePosition(term[sep+2:], n)
+		if err != nil {
+			return nil, err
+		}
+		if from > to {
+			return nil, fmt.Errorf("invalid range: %q", term)
+		}
+		return seq(from, to), nil
+	}
+	idx, err := parsePosition(term, n)
+	if err != nil {
+		return nil, err
+	}
+	return []int{idx}, nil
+}
+
+// parsePosition returns index of item at position like "3", "$" or "-2"
+// among n items.
+func parsePosition(pos string, n int) (int, error) {
+	var idx int
+	switch {
+	case pos == "$":
+		idx = n - 1
+	case strings.HasPrefix(pos, "-"):
+		p, err := strconv.Atoi(pos[1:])
+		if err != nil || p < 1 {
+			return 0, fmt.Errorf("invalid index: %q", pos)
+		}
+		idx = n - p
+	default:
+		p, err := strconv.Atoi(pos)
+		if err != nil {
+			return 0, fmt.Errorf("invalid index: %q", pos)
+		}
+		idx = p - 1
+	}
+	if idx < 0 || idx >= n {
+		return 0, fmt.Errorf("index out of range: %q", pos)
+	}
+	return idx, nil
+}
+
+// matching returns indexes of items with name accepted by match. Term is
+// the selector term used in error message.
+func matching(term string, items []selectable, match func(string) bool) ([]int, error) {
+	var idxs []int
+	for i, item := range items {
+		if match(item.name) {
+			idxs = append(idxs, i)
+		}
+	}
+	if len(idxs) == 0 {
+		return nil, fmt.Errorf("nothing matches %s", term)
+	}
+	return idxs, nil
+}
+
+// fuzzyMatch reports whether name contains all letters of text in the same
+// order, ignoring case.
+func fuzzyMatch(text, name string) bool {
+	name = strings.ToLower(name)
+	for _, r := range strings.ToLower(text) {
+		i := strings.IndexRune(name, r)
+		if i == -1 {
+			return false
+		}
+		name = name[i+utf8.RuneLen(r):]
+	}
+	return true
+}
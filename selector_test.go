@@ -0,0 +1,117 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	items := []selectable{
+		{10, "standup"},
+		{11, "coffee with Joe"},
+		{12, "review PRs, then merge"},
+		{15, "call mom"},
+		{20, "Standup notes"},
+	}
+	tests := []struct {
+		selector string
+		want     []int
+	}{
+		{"1", []int{0}},
+		{"5", []int{4}},
+		{"*", []int{0, 1, 2, 3, 4}},
+		{"1-3", []int{0, 1, 2}},
+		{"2,5,4", []int{1, 3, 4}},
+		{"3,1-3", []int{0, 1, 2}},
+		{"$", []int{4}},
+		{"-2", []int{3}},
+		{"-3-$", []int{2, 3, 4}},
+		{"2--2", []int{1, 2, 3}},
+		{"*,^4", []int{0, 1, 2, 4}},
+		{"*,^1-2,^$", []int{2, 3}},
+		{"#15", []int{3}},
+		{"#10,#20", []int{0, 4}},
+		{"*,^#11", []int{0, 2, 3, 4}},
+		{"/standup/", []int{0}},
+		{"/(?i)standup/", []int{0, 4}},
+		{"/PRs, then/", []int{2}},
+		{"/^c/,1", []int{0, 1, 3}},
+		{"*,^/standup/", []int{1, 2, 3, 4}},
+		{"~coffee", []int{1}},
+		{"~cwj", []int{1}},
+		{"~STAND", []int{0, 4}},
+	}
+
+	for _, test := range tests {
+		idxs, err := parseSelector(test.selector, items)
+		if err != nil {
+			t.Errorf("parseSelector(%q) failed: %v", test.selector, err)
+			continue
+		}
+		if !reflect.DeepEqual(idxs, test.want) {
+			t.Errorf("parseSelector(%q) = %v, want: %v", test.selector, idxs, test.want)
+		}
+	}
+}
+
+func TestParseSelectorError(t *testing.T) {
+	items := []selectable{{10, "standup"}, {11, "coffee"}, {12, "call mom"}}
+	tests := []struct {
+		selector string
+		want     string
+	}{
+		{"", "empty selector"},
+		{"1,", "empty selector"},
+		{"0", `index out of range: "0"`},
+		{"4", `index out of range: "4"`},
+		{"-4", `index out of range: "-4"`},
+		{"-0", `invalid index: "-0"`},
+		{"x", `invalid index: "x"`},
+		{"3-1", `invalid range: "3-1"`},
+		{"1-x", `invalid index: "x"`},
+		{"#x", `invalid ID: "#x"`},
+		{"#13", "no item with ID #13"},
+		{"^2", errNothingSelected.Error()},
+		{"/standup", `unterminated regular expression: "/standup"`},
+		{"/[/", "invalid regular expression: error parsing regexp: missing closing ]: `[`"},
+		{"/tea/", "nothing matches /tea/"},
+		{"~", `empty text: "~"`},
+		{"~tea", "nothing matches ~tea"},
+	}
+
+	for _, test := range tests {
+		_, err := parseSelector(test.selector, items)
+		if err == nil {
+			t.Errorf("parseSelector(%q) succeeded, want error: %s", test.selector, test.want)
+			continue
+		}
+		if err.Error() != test.want {
+			t.Errorf("parseSelector(%q) error = %q, want: %q", test.selector, err, test.want)
+		}
+	}
+}
+
+func TestParseSelectorEmpty(t *testing.T) {
+	idxs, err := parseSelector("*", nil)
+	if err != nil || len(idxs) != 0 {
+		t.Errorf("parseSelector(\"*\") = %v, %v, want no items", idxs, err)
+	}
+}
+
+func TestHasText(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"1-3", false},
+		{"*,^/x/", false},
+		{"/x/", true},
+		{"1,~x", true},
+	}
+
+	for _, test := range tests {
+		if got := hasText(test.selector); got != test.want {
+			t.Errorf("hasText(%q) = %v, want: %v", test.selector, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+// Package timeparse parses human-friendly time expressions like "+10m",
+// "@9:00", "tomorrow 9am" or "next monday 14:30".
+package timeparse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultHour is the hour used when expression specifies only a day.
+const defaultHour = 9
+
+// endOfDayHour is the hour meant by "eod".
+const endOfDayHour = 17
+
+// An Error describes a token which couldn't be parsed.
+type Error struct {
+	Token string
+}
+
+func (e *Error) Error() string {
+	if e.Token == "" {
+		return "invalid time: unexpected end of input"
+	}
+	return fmt.Sprintf("invalid time: cannot parse %q", e.Token)
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"sun":       time.Sunday,
+	"monday":    time.Monday,
+	"mon":       time.Monday,
+	"tuesday":   time.Tuesday,
+	"tue":       time.Tuesday,
+	"wednesday": time.Wednesday,
+	"wed":       time.Wednesday,
+	"thursday":  time.Thursday,
+	"thu":       time.Thursday,
+	"friday":    time.Friday,
+	"fri":       time.Friday,
+	"saturday":  time.Saturday,
+	"sat":       time.Saturday,
+}
+
+var units = map[string]time.Duration{
+	"s":       time.Second,
+	"sec":     time.Second,
+	"second":  time.Second,
+	"seconds": time.Second,
+	"m":       time.Minute,
+	"min":     time.Minute,
+	"minute":  time.Minute,
+	"minutes": time.Minute,
+	"h":       time.Hour,
+	"hour":    time.Hour,
+	"hours":   time.Hour,
+	"d":       time.Hour * 24,
+	"day":     time.Hour * 24,
+	"days":    time.Hour * 24,
+	"w":       time.Hour * 24 * 7,
+	"week":    time.Hour * 24 * 7,
+	"weeks":   time.Hour * 24 * 7,
+}
+
+var durationRe = regexp.MustCompile(`^(\d+)(mo|[smhdw])`)
+
+var clockRe = regexp.MustCompile(`^(\d{1,2})(?::(\d{2})(?::(\d{2}))?)?(am|pm)?$`)
+
+var dateRe = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+var monthDayRe = regexp.MustCompile(`^\d{2}-\d{2}$`)
+
+// Parse returns time described by input, relative to now. Supported
+// expressions:
+//
+//	+10m, +1h30m, +2w3d    relative time in seconds (s), minutes (m), hours
+//	                       (h), days (d), weeks (w) or months (mo)
+//	@9:00                  the nearest future occurrence of the time
+//	@9:00:30               the same, with seconds
+//	@today 8:00, @-8:00    time of the current day, even if it has passed
+//	@2026-12-24 18:00      date with optional time, 9:00 by default
+//	@12-24 18:00           the nearest future occurrence of the date with
+//	                       optional time
+//	@mon 9:00              the nearest future weekday with optional time
+//	14:30, 9am, 9:30 pm    the nearest future occurrence of the time
+//	today 18:00, tomorrow  day with optional time, 9:00 by default
+//	friday, next mon 9am   the nearest following weekday with optional time
+//	in 2 weeks             relative time in words
+//	eod                    the nearest future end of the working day
+//	                       (17:00)
+//	2026-11-03 08:00       date with optional time
+func Parse(input string, now time.Time) (time.Time, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return time.Time{}, &Error{}
+	}
+	if input[0] == '+' {
+		return parseRelative(input, now)
+	}
+	p := parser{tokens: strings.Fields(strings.ToLower(input)), now: now}
+	if input[0] == '@' {
+		p.tokens[0] = p.tokens[0][1:]
+		if p.tokens[0] == "" {
+			p.tokens = p.tokens[1:]
+		}
+		return p.parseAt()
+	}
+	return p.parse()
+}
+
+// parseRelative parses sequence of durations like "+1h30m".
+func parseRelative(input string, now time.Time) (time.Time, error) {
+	rest := input[1:]
+	if rest == "" {
+		return time.Time{}, &Error{Token: input}
+	}
+	t := now
+	for rest != "" {
+		match := durationRe.FindStringSubmatch(rest)
+		if match == nil {
+			return time.Time{}, &Error{Token: strings.TrimSpace(rest)}
+		}
+		num, err := strconv.Atoi(match[1])
+		if err != nil {
+			return time.Time{}, &Error{Token: rest}
+		}
+		if match[2] == "mo" {
+			t = t.AddDate(0, num, 0)
+		} else {
+			t = t.Add(units[match[2]] * time.Duration(num))
+		}
+		rest = rest[len(match[0]):]
+	}
+	return t, nil
+}
+
+type parser struct {
+	tokens []string
+	now    time.Time
+}
+
+// next removes and returns the first token. Empty string is returned if
+// there are no tokens left.
+func (p *parser) next() string {
+	if len(p.tokens) == 0 {
+		return ""
+	}
+	tok := p.tokens[0]
+	p.tokens = p.tokens[1:]
+	return tok
+}
+
+// end fails if there are tokens left.
+func (p *parser) end() error {
+	if len(p.tokens) > 0 {
+		return &Error{Token: p.tokens[0]}
+	}
+	return nil
+}
+
+func (p *parser) today() time.Time {
+	return time.Date(p.now.Year(), p.now.Month(), p.now.Day(), 0, 0, 0, 0, p.now.Location())
+}
+
+func (p *parser) parse() (time.Time, error) {
+	tok := p.next()
+	var day time.Time
+	bare := false
+	switch {
+	case tok == "in":
+		return p.parseIn()
+	case tok == "eod":
+		if err := p.end(); err != nil {
+			return time.Time{}, err
+		}
+		return p.rollForward(at(p.today(), endOfDayHour, 0, 0)), nil
+	case tok == "today":
+		day = p.today()
+	case tok == "tomorrow":
+		day = p.today().AddDate(0, 0, 1)
+	case tok == "next":
+		tok = p.next()
+		wd, ok := weekdays[tok]
+		if !ok {
+			return time.Time{}, &Error{Token: tok}
+		}
+		day = p.nextWeekday(wd)
+	case dateRe.MatchString(tok):
+		t, err := time.ParseInLocation("2006-01-02", tok, p.now.Location())
+		if err != nil {
+			return time.Time{}, &Error{Token: tok}
+		}
+		day = t
+	default:
+		if wd, ok := weekdays[tok]; ok {
+			day = p.nextWeekday(wd)
+			break
+		}
+		// Bare time, today or tomorrow if it has passed.
+		p.tokens = append([]string{tok}, p.tokens...)
+		day = p.today()
+		bare = true
+	}
+	hour, min, sec := defaultHour, 0, 0
+	if len(p.tokens) > 0 {
+		var err error
+		hour, min, sec, err = p.parseClock()
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	if err := p.end(); err != nil {
+		return time.Time{}, err
+	}
+	if bare {
+		return p.rollForward(at(day, hour, min, sec)), nil
+	}
+	return at(day, hour, min, sec), nil
+}
+
+// rollForward returns t moved to the next day if it isn't in the future.
+func (p *parser) rollForward(t time.Time) time.Time {
+	if t.After(p.now) {
+		return t
+	}
+	return at(t.AddDate(0, 0, 1), t.Hour(), t.Minute(), t.Second())
+}
+
+// parseAt parses expression following "@". Unless the day is given
+// explicitly, the nearest future occurrence is returned.
+func (p *parser) parseAt() (time.Time, error) {
+	tok := p.next()
+	day := p.today()
+	weekday := false
+	monthDay := false
+	clockRequired := false
+	rollForward := true
+	switch {
+	case tok == "today":
+		rollForward = false
+	case strings.HasPrefix(tok, "-"):
+		p.tokens = append([]string{tok[1:]}, p.tokens...)
+		clockRequired = true
+		rollForward = false
+	case dateRe.MatchString(tok):
+		t, err := time.ParseInLocation("2006-01-02", tok, p.now.Location())
+		if err != nil {
+			return time.Time{}, &Error{Token: tok}
+		}
+		day = t
+	case monthDayRe.MatchString(tok):
+		t, err := time.ParseInLocation("2006-01-02", fmt.Sprintf("%d-%s", p.now.Year(), tok), p.now.Location())
+		if err != nil {
+			return time.Time{}, &Error{Token: tok}
+		}
+		day = t
+		monthDay = true
+	default:
+		if wd, ok := weekdays[tok]; ok {
+			days := (int(wd) - int(p.now.Weekday()) + 7) % 7
+			day = p.today().AddDate(0, 0, days)
+			weekday = true
+			break
+		}
+		// Time of the current day.
+		p.tokens = append([]string{tok}, p.tokens...)
+		clockRequired = true
+	}
+	hour, min, sec := defaultHour, 0, 0
+	if len(p.tokens) > 0 || clockRequired {
+		var err error
+		hour, min, sec, err = p.parseClock()
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	if err := p.end(); err != nil {
+		return time.Time{}, err
+	}
+	t := at(day, hour, min, sec)
+	if rollForward && !t.After(p.now) {
+		switch {
+		case weekday:
+			t = at(day.AddDate(0, 0, 7), hour, min, sec)
+		case monthDay:
+			t = at(day.AddDate(1, 0, 0), hour, min, sec)
+		case clockRequired:
+			t = at(day.AddDate(0, 0, 1), hour, min, sec)
+		}
+	}
+	return t, nil
+}
+
+// at returns time on the given day.
+func at(day time.Time, hour, min, sec int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, min, sec, 0, day.Location())
+}
+
+// nextWeekday returns the nearest day after today which is wd.
+func (p *parser) nextWeekday(wd time.Weekday) time.Time {
+	days := (int(wd) - int(p.now.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return p.today().AddDate(0, 0, days)
+}
+
+// parseIn parses "in N unit" expression without the leading "in".
+func (p *parser) parseIn() (time.Time, error) {
+	tok := p.next()
+	num, err := strconv.Atoi(tok)
+	if err != nil || num < 0 {
+		return time.Time{}, &Error{Token: tok}
+	}
+	tok = p.next()
+	unit, ok := units[tok]
+	if !ok {
+		return time.Time{}, &Error{Token: tok}
+	}
+	if err := p.end(); err != nil {
+		return time.Time{}, err
+	}
+	return p.now.Add(unit * time.Duration(num)), nil
+}
+
+// parseClock parses time of the day like "14:30", "14:30:15", "9am" or
+// "9:30 pm".
+func (p *parser) parseClock() (hour, min, sec int, err error) {
+	tok := p.next()
+	if len(p.tokens) > 0 && (p.tokens[0] == "am" || p.tokens[0] == "pm") {
+		tok += p.next()
+	}
+	match := clockRe.FindStringSubmatch(tok)
+	if match == nil || (match[2] == "" && match[4] == "") {
+		return 0, 0, 0, &Error{Token: tok}
+	}
+	hour, _ = strconv.Atoi(match[1])
+	if match[2] != "" {
+		min, _ = strconv.Atoi(match[2])
+	}
+	if match[3] != "" {
+		sec, _ = strconv.Atoi(match[3])
+	}
+	switch match[4] {
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return 0, 0, 0, &Error{Token: tok}
+		}
+		hour %= 12
+		if match[4] == "pm" {
+			hour += 12
+		}
+	}
+	if hour > 23 || min > 59 || sec > 59 {
+		return 0, 0, 0, &Error{Token: tok}
+	}
+	return hour, min, sec, nil
+}
@@ -0,0 +1,127 @@
+package timeparse
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	// Wednesday.
+	now := time.Date(2026, 10, 14, 10, 30, 0, 0, time.UTC)
+	date := func(month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"+10s", now.Add(10 * time.Second)},
+		{"+4d", now.Add(4 * 24 * time.Hour)},
+		{"+1h30m", now.Add(90 * time.Minute)},
+		{"+2w3d", now.Add(17 * 24 * time.Hour)},
+		{"+1mo", date(11, 14, 10, 30)},
+		{"+1mo2d", date(11, 16, 10, 30)},
+		{"@9:10", date(10, 15, 9, 10)},
+		{"@23:15", date(10, 14, 23, 15)},
+		{"@-9:10", date(10, 14, 9, 10)},
+		{"@-9:10:15", time.Date(2026, 10, 14, 9, 10, 15, 0, time.UTC)},
+		{"@10:30:45", time.Date(2026, 10, 14, 10, 30, 45, 0, time.UTC)},
+		{"@today 9:10", date(10, 14, 9, 10)},
+		{"@today", date(10, 14, 9, 0)},
+		{"@10-01", time.Date(2027, 10, 1, 9, 0, 0, 0, time.UTC)},
+		{"@2026-12-24", date(12, 24, 9, 0)},
+		{"@2026-12-24 18:00", date(12, 24, 18, 0)},
+		{"@12-24 18:00", date(12, 24, 18, 0)},
+		{"@mon 9:00", date(10, 19, 9, 0)},
+		{"@wed 11:00", date(10, 14, 11, 0)},
+		{"@wed 9:00", date(10, 21, 9, 0)},
+		{"@fri", date(10, 16, 9, 0)},
+		{"14:30", date(10, 14, 14, 30)},
+		{"9am", date(10, 15, 9, 0)},
+		{"10:30", date(10, 15, 10, 30)},
+		{"9:30 PM", date(10, 14, 21, 30)},
+		{"12am", date(10, 15, 0, 0)},
+		{"today 18:00", date(10, 14, 18, 0)},
+		{"tomorrow", date(10, 15, 9, 0)},
+		{"tomorrow 9am", date(10, 15, 9, 0)},
+		{"friday", date(10, 16, 9, 0)},
+		{"wed", date(10, 21, 9, 0)},
+		{"next monday 14:30", date(10, 19, 14, 30)},
+		{"in 2 weeks", now.Add(14 * 24 * time.Hour)},
+		{"in 1 hour", now.Add(time.Hour)},
+		{"eod", date(10, 14, 17, 0)},
+		{"2026-11-03 08:00", date(11, 3, 8, 0)},
+		{"2026-11-03", date(11, 3, 9, 0)},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.input, now)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", test.input, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("Parse(%q) = %v, want: %v", test.input, got, test.want)
+		}
+	}
+}
+
+func TestParseRollForward(t *testing.T) {
+	now := time.Date(2026, 10, 14, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input string
+		want  time.Time
+	}{
+		{"eod", time.Date(2026, 10, 15, 17, 0, 0, 0, time.UTC)},
+		{"18:00", time.Date(2026, 10, 15, 18, 0, 0, 0, time.UTC)},
+		{"18:00:01", time.Date(2026, 10, 14, 18, 0, 1, 0, time.UTC)},
+		{"today 17:00", time.Date(2026, 10, 14, 17, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.input, now)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", test.input, err)
+			continue
+		}
+		if !got.Equal(test.want) {
+			t.Errorf("Parse(%q) = %v, want: %v", test.input, got, test.want)
+		}
+	}
+}
+
+func TestParseError(t *testing.T) {
+	now := time.Date(2026, 10, 14, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		input string
+		token string
+	}{
+		{"", ""},
+		{"tomorow", "tomorow"},
+		{"tomorrow 25:00", "25:00"},
+		{"next month", "month"},
+		{"in 2 fortnights", "fortnights"},
+		{"in two weeks", "two"},
+		{"friday 9am sharp", "sharp"},
+		{"13pm", "13pm"},
+		{"@9", "9"},
+		{"@9:10:60", "9:10:60"},
+		{"@9:00 tomorrow", "tomorrow"},
+		{"@13-45", "13-45"},
+		{"@mon noon", "noon"},
+		{"+", "+"},
+		{"+1h30", "30"},
+		{"+1h 30m", "30m"},
+		{"+1x", "1x"},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.input, now)
+		var perr *Error
+		if !errors.As(err, &perr) {
+			t.Errorf("Parse(%q) error = %v, want *Error", test.input, err)
+			continue
+		}
+		if perr.Token != test.token {
+			t.Errorf("Parse(%q) failed on %q, want: %q", test.input, perr.Token, test.token)
+		}
+	}
+}
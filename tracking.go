@@ -0,0 +1,129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Interval is a period of work on todo. Zero End means that work is still in
+// progress.
+type Interval struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Tracking reports whether work on todo is in progress.
+func (t *Todo) Tracking() bool {
+	n := len(t.Intervals)
+	return n > 0 && t.Intervals[n-1].End.IsZero()
+}
+
+// StartTracking opens a new interval unless one is already open.
+func (t *Todo) StartTracking(now time.Time) {
+	if t.Tracking() {
+		return
+	}
+	t.Intervals = append(t.Intervals, Interval{Start: now})
+}
+
+// StopTracking closes the open interval.
+func (t *Todo) StopTracking(now time.Time) {
+	if !t.Tracking() {
+		return
+	}
+	intervals := make([]Interval, len(t.Intervals))
+	copy(intervals, t.Intervals)
+	intervals[len(intervals)-1].End = now
+	t.Intervals = intervals
+}
+
+// Tracked returns time spent on todo between from and to. Zero from means
+// the beginning of time. Open interval lasts until now.
+func (t *Todo) Tracked(from, to, now time.Time) time.Duration {
+	var total time.Duration
+	for _, i := range t.Intervals {
+		start, end := i.Start, i.End
+		if end.IsZero() {
+			end = now
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+	}
+	return total
+}
+
+var errInvalidRange = errors.New("invalid range")
+
+// parseRange returns the start of report range: "today", "week" (since
+// Monday), "month" or "all".
+func parseRange(name string, now time.Time) (time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch name {
+	case "today":
+		return today, nil
+	case "week":
+		return today.AddDate(0, 0, -(int(today.Weekday())+6)%7), nil
+	case "month":
+		return today.AddDate(0, 0, 1-today.Day()), nil
+	case "all":
+		return time.Time{}, nil
+	}
+	return time.Time{}, errInvalidRange
+}
+
+// A reportEntry holds time spent on todos with the same name or tag.
+type reportEntry struct {
+	name  string
+	total time.Duration
+}
+
+// report sums up time spent on todos since from. Entries are sorted from the
+// most time-consuming one.
+func report(todos []Todo, from, now time.Time) (byTodo, byTag []reportEntry) {
+	todoTotals := make(map[string]time.Duration)
+	tagTotals := make(map[string]time.Duration)
+	for _, todo := range todos {
+		d := todo.Tracked(from, now, now)
+		if d == 0 {
+			continue
+		}
+		todoTotals[todo.Name] += d
+		for _, tag := range todo.Tags {
+			tagTotals["+"+tag] += d
+		}
+	}
+	return sortedEntries(todoTotals), sortedEntries(tagTotals)
+}
+
+func sortedEntries(totals map[string]time.Duration) []reportEntry {
+	entries := make([]reportEntry, 0, len(totals))
+	for name, total := range totals {
+		entries = append(entries, reportEntry{name: name, total: total})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].total != entries[j].total {
+			return entries[i].total > entries[j].total
+		}
+		return entries[i].name < entries[j].name
+	})
+	return entries
+}
+
+// formatElapsed returns d like "1:02:03" or "2:03".
+func formatElapsed(d time.Duration) string {
+	d = d.Round(time.Second)
+	h, m, s := int(d/time.Hour), int(d/time.Minute)%60, int(d/time.Second)%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
@@ -4,13 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"regexp"
 	"sort"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	gsq "github.com/kballard/go-shellquote"
 	"github.com/mattn/go-runewidth"
+	"github.com/mlowicki/termtodo/timeparse"
 	"github.com/nsf/termbox-go"
 )
 
@@ -19,17 +21,36 @@ type View int
 const (
 	TODOS View = iota
 	TRIGGERS
+	HISTORY
+	LISTS
+	REPORT
 )
 
 type UI struct {
-	cl        *CommandLine
-	Scheduler *Scheduler
-	todos     []Todo
-	triggers  []Trigger
-	blinkt    *Blinkt
-	err       error
-	cancelErr func()
-	view      View
+	cl            *CommandLine
+	Scheduler     *Scheduler
+	todos         []Todo
+	triggers      []Trigger
+	history       []Todo
+	historyDay    time.Time         // zero value shows the whole history.
+	filter        Query             // query which todos and triggers must match to be shown.
+	filterText    string            // text of filter query.
+	views         map[string]string // saved filter queries by name.
+	list          string            // name of the active list.
+	editing       string            // ID of trigger updated by the next add command.
+	pending       []string          // command run once user confirms it.
+	searchQuery   string            // text searched for in names of listed items.
+	selected      int               // short ID of the current search match.
+	target        string            // selector used when command doesn't specify one.
+	scroll        int               // number of items scrolled off the top.
+	reportRange   string            // range of time shown by the report view.
+	blinkt        *Blinkt
+	blinktPattern Pattern
+	pomo          *pomodoro      // running pomodoro, set by goroutine started in NewUI and read by Redraw.
+	pomoCh        chan *pomodoro // starts (or stops if nil) pomodoro.
+	msg           string
+	cancelMsg     func()
+	view          View
 }
 
 func NewUI(scheduler *Scheduler) *UI {
@@ -37,18 +58,62 @@ func NewUI(scheduler *Scheduler) *UI {
 	if err != nil {
 		panic(err) // TODO more desciptive message
 	}
-	ui := UI{cl: &CommandLine{}, Scheduler: scheduler, view: TODOS}
+	ui := UI{cl: NewCommandLine(), Scheduler: scheduler, view: TODOS, list: defaultList, pomoCh: make(chan *pomodoro)}
 	go func() {
 		for {
+			var dueCh <-chan time.Time
+			if d, ok := ui.untilDue(time.Now()); ok {
+				dueCh = time.After(d)
+			}
+			var pomoEndCh <-chan time.Time
+			if ui.pomo != nil {
+				pomoEndCh = time.After(time.Until(ui.pomo.end))
+			}
+			// Elapsed time of tracked todo and pomodoro countdown are
+			// updated every second.
+			var tickCh <-chan time.Time
+			if ui.tracking() || ui.pomo != nil {
+				tickCh = time.After(time.Second)
+			}
 			select {
+			case <-dueCh:
+				ui.Redraw()
+			case <-tickCh:
+				ui.Redraw()
+			case pomo := <-ui.pomoCh:
+				ui.pomo = pomo
+				ui.Redraw()
+			case <-pomoEndCh:
+				ui.nextPomoPeriod(time.Now())
 			case todos := <-scheduler.TodosCh:
-				sort.SliceStable(todos, func(i, j int) bool { return todos[i].CreatedAt.Before(todos[j].CreatedAt) })
+				sort.SliceStable(todos, func(i, j int) bool {
+					if todos[i].Priority != todos[j].Priority {
+						return priorityRank(todos[i].Priority) < priorityRank(todos[j].Priority)
+					}
+					return todos[i].CreatedAt.Before(todos[j].CreatedAt)
+				})
 				ui.todos = todos
+				if ui.pomo != nil {
+					if _, ok := ui.todo(ui.pomo.todoID); !ok {
+						ui.pomo = nil
+						ui.showMsg("pomodoro stopped")
+					}
+				}
 				ui.Redraw()
 			case triggers := <-scheduler.TriggersCh:
 				sort.SliceStable(triggers, func(i, j int) bool { return triggers[i].Name < triggers[j].Name })
 				ui.triggers = triggers
 				ui.Redraw()
+			case history := <-scheduler.HistoryCh:
+				sort.SliceStable(history, func(i, j int) bool { return history[i].CompletedAt.After(history[j].CompletedAt) })
+				ui.history = history
+				ui.Redraw()
+			case views := <-scheduler.ViewsCh:
+				ui.views = views
+			case err := <-scheduler.ErrCh:
+				ui.showErr(err)
+			case notice := <-scheduler.NoticeCh:
+				ui.showMsg(notice)
 			}
 		}
 	}()
@@ -57,77 +122,352 @@ func NewUI(scheduler *Scheduler) *UI {
 
 // showErr displays error message to user.
 func (ui *UI) showErr(err error) {
-	if ui.cancelErr != nil {
-		ui.cancelErr()
+	ui.showMsg(err.Error())
+}
+
+// showMsg displays message to user for a few seconds.
+func (ui *UI) showMsg(msg string) {
+	if ui.cancelMsg != nil {
+		ui.cancelMsg()
 	}
 	termbox.Flush()
 	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
 		select {
 		case <-time.After(time.Second * 5):
-			ui.clearErr()
+			ui.clearMsg()
 			cancel()
 		case <-ctx.Done():
 		}
 	}()
-	ui.cancelErr = cancel
-	ui.err = err
+	ui.cancelMsg = cancel
+	ui.msg = msg
 	ui.Redraw()
 }
 
-// clearErr hides error message.
-func (ui *UI) clearErr() {
+// clearMsg hides message.
+func (ui *UI) clearMsg() {
 	w, h := termbox.Size()
 	fill(0, h-2, w, 1, termbox.Cell{Ch: ' '})
-	ui.err = nil
+	ui.msg = ""
 	termbox.Flush()
 }
 
 func (ui *UI) print(x, y int, text string) {
-	col := termbox.ColorDefault
+	ui.printColor(x, y, termbox.ColorDefault, text)
+}
+
+func (ui *UI) printColor(x, y int, fg termbox.Attribute, text string) {
 	for _, r := range text {
-		termbox.SetCell(x, y, r, col, col)
+		termbox.SetCell(x, y, r, fg, termbox.ColorDefault)
 		x += runewidth.RuneWidth(r)
 	}
 }
 
+// listHeight returns the number of items which fit on the screen.
+func listHeight() int {
+	_, h := termbox.Size()
+	return h - 2 // message and command lines.
+}
+
+// printItem prints i-th item of the active view. Items scrolled off the
+// screen are skipped. The current search match is shown in reverse video
+// and other matches are underlined.
+func (ui *UI) printItem(i, id int, name string, fg termbox.Attribute, text string) {
+	y := i - ui.scroll
+	if y < 0 || y >= listHeight() {
+		return
+	}
+	switch {
+	case ui.selected != 0 && id == ui.selected:
+		fg |= termbox.AttrReverse
+	case ui.matches(name):
+		fg |= termbox.AttrUnderline
+	}
+	ui.printColor(0, y, fg, text)
+}
+
+// matches reports whether name contains search query, ignoring case.
+func (ui *UI) matches(name string) bool {
+	return ui.searchQuery != "" && strings.Contains(strings.ToLower(name), strings.ToLower(ui.searchQuery))
+}
+
+// search selects item matching the search query. Screen is scrolled if the
+// match isn't visible.
+func (ui *UI) search(s Search) {
+	defer ui.Redraw()
+	prev := ui.selected
+	ui.searchQuery = s.Query
+	ui.selected = 0
+	if ui.view != TODOS && ui.view != TRIGGERS {
+		ui.searchQuery = ""
+		if s.Query != "" {
+			ui.showErr(errors.New("search works only in todos and triggers views"))
+		}
+		return
+	}
+	items := ui.viewItems()
+	cur := -1
+	var matches []int
+	for i, item := range items {
+		if ui.matches(item.name) {
+			matches = append(matches, i)
+		}
+		if item.id == prev {
+			cur = i
+		}
+	}
+	if len(matches) == 0 {
+		ui.scroll = 0
+		return
+	}
+	idx := matches[0]
+	switch s.Step {
+	case 0:
+		// The current match is kept while query is typed.
+		for _, m := range matches {
+			if m >= cur {
+				idx = m
+				break
+			}
+		}
+	case 1:
+		for _, m := range matches {
+			if m > cur {
+				idx = m
+				break
+			}
+		}
+	case -1:
+		idx = matches[len(matches)-1]
+		for _, m := range matches {
+			if m < cur {
+				idx = m
+			}
+		}
+	}
+	ui.selected = items[idx].id
+	if rows := listHeight(); idx < ui.scroll {
+		ui.scroll = idx
+	} else if idx >= ui.scroll+rows {
+		ui.scroll = idx - rows + 1
+	}
+}
+
+// priorityRank returns the position of priority in the order todos are
+// sorted, placing todos without priority last.
+func priorityRank(priority int) int {
+	if priority == 0 {
+		return maxPriority + 1
+	}
+	return priority
+}
+
+// priorityColor returns the color used to render items with priority.
+func priorityColor(priority int) termbox.Attribute {
+	switch priority {
+	case 1:
+		return termbox.ColorMagenta | termbox.AttrBold
+	case 2:
+		return termbox.ColorYellow
+	case 3:
+		return termbox.ColorCyan
+	default:
+		return termbox.ColorDefault
+	}
+}
+
+// label returns the name of item followed by its attributes.
+func label(name string, priority int, tags []string) string {
+	if priority != 0 {
+		name += fmt.Sprintf(" !%d", priority)
+	}
+	for _, tag := range tags {
+		name += " +" + tag
+	}
+	return name
+}
+
 func (ui *UI) Redraw() {
 	termbox.Clear(termbox.ColorDefault, termbox.ColorDefault)
 	switch ui.view {
 	case TODOS:
-		for i, todo := range ui.todos {
-			ui.print(0, i, fmt.Sprintf("%*d %s", -len(strconv.Itoa(len(ui.todos))), i+1, todo.Name))
+		todos := ui.visibleTodos()
+		now := time.Now()
+		idWidth := ui.idWidth()
+		for i, todo := range todos {
+			name := label(todo.Name, todo.Priority, todo.Tags)
+			if len(todo.Subtasks) > 0 {
+				name += fmt.Sprintf(" [%d/%d]", len(todo.Subtasks)-ui.subtasksLeft(todo), len(todo.Subtasks))
+			}
+			if todo.Repeat > 1 {
+				name += fmt.Sprintf(" ×%d", todo.Repeat)
+			}
+			if todo.Parent != "" {
+				name = "  [ ] " + todo.Name
+			}
+			if todo.Pomodoros > 0 {
+				name += fmt.Sprintf(" (%d pomodoros)", todo.Pomodoros)
+			}
+			if todo.Tracking() {
+				name += " (tracking " + formatElapsed(todo.Tracked(time.Time{}, now, now)) + ")"
+			}
+			text := fmt.Sprintf("%*d #%*d %s", -len(strconv.Itoa(len(todos))), i+1, -idWidth, todo.ShortID, name)
+			color := priorityColor(todo.Priority)
+			if !todo.Due.IsZero() {
+				text += " (due " + todo.Due.Format("Mon Jan 2 15:04") + ")"
+				if todo.Due.Before(now) {
+					color = termbox.ColorRed
+				}
+			}
+			if blockers := ui.blockers(todo); len(blockers) > 0 {
+				text += " (blocked by " + strings.Join(blockers, ", ") + ")"
+				color = termbox.ColorBlack | termbox.AttrBold
+			}
+			ui.printItem(i, todo.ShortID, todo.Name, color, text)
 		}
 	case TRIGGERS:
+		triggers := ui.visibleTriggers()
+		idWidth := ui.idWidth()
 		maxName := 0
-		for _, trigger := range ui.triggers {
-			w := len(trigger.Name)
+		for _, trigger := range triggers {
+			w := len(label(trigger.Name, trigger.Priority, trigger.Tags))
 			if w > maxName {
 				maxName = w
 			}
 		}
-		for i, trigger := range ui.triggers {
+		for i, trigger := range triggers {
 			when := trigger.Cron
-			if trigger.Count != -1 {
-				when = trigger.After.Format("Mon Jan 2 15:04:05")
+			if trigger.Every != 0 && !trigger.OneTime() {
+				when = "waiting for todo to be done"
+			} else if trigger.OneTime() {
+				when = trigger.After.Local().Format("Mon Jan 2 15:04:05")
+				if trigger.TZ != "" {
+					when += " (" + trigger.TZ + ")"
+				}
+			} else if trigger.TZ != "" {
+				when += " (" + trigger.TZ + ", next " + trigger.Next().Local().Format("Mon Jan 2 15:04:05") + ")"
+			}
+			if trigger.DueIn != 0 {
+				when += " due:" + formatDuration(trigger.DueIn)
+			}
+			if len(trigger.Subtasks) > 0 {
+				when += fmt.Sprintf(" (%d subtasks)", len(trigger.Subtasks))
+			}
+			if trigger.Count > 0 && !trigger.OneTime() && trigger.Every == 0 {
+				when += fmt.Sprintf(" (%d left)", trigger.Count)
 			}
-			ui.print(0, i, fmt.Sprintf("%*d %*s %s", -len(strconv.Itoa(len(ui.triggers))), i+1, -maxName, trigger.Name, when))
+			if !trigger.Until.IsZero() {
+				when += " (until " + trigger.Until.Local().Format("Mon Jan 2 15:04") + ")"
+			}
+			if trigger.Every != 0 {
+				when += " every:" + strings.TrimPrefix(formatDuration(trigger.Every), "+")
+			}
+			if trigger.CatchUp != CatchUpOne {
+				when += " catchup:" + trigger.CatchUp.String()
+			}
+			if trigger.Overlap != OverlapNew {
+				when += " overlap:" + trigger.Overlap.String()
+			}
+			color := priorityColor(trigger.Priority)
+			if trigger.Paused {
+				when += " (paused"
+				if !trigger.ResumeAt.IsZero() {
+					when += " until " + trigger.ResumeAt.Format("Mon Jan 2 15:04")
+				}
+				when += ")"
+				color = termbox.ColorBlack | termbox.AttrBold
+			}
+			text := fmt.Sprintf("%*d #%*d %*s %s", -len(strconv.Itoa(len(triggers))), i+1, -idWidth, trigger.ShortID, -maxName, label(trigger.Name, trigger.Priority, trigger.Tags), when)
+			ui.printItem(i, trigger.ShortID, trigger.Name, color, text)
 		}
-	}
-
-	if len(ui.todos) > 0 {
-		if ui.blinkt == nil {
-			ui.blinkt = NewBlinkt()
+	case HISTORY:
+		history := ui.visibleHistory()
+		maxName := 0
+		for _, todo := range history {
+			w := len(todo.Name)
+			if w > maxName {
+				maxName = w
+			}
 		}
-	} else {
-		if ui.blinkt != nil {
-			ui.blinkt.Stop()
-			ui.blinkt = nil
+		idWidth := ui.idWidth()
+		for i, todo := range history {
+			when := todo.CompletedAt.Format("Mon Jan 2 15:04:05")
+			ui.print(0, i, fmt.Sprintf("%*d #%*d %*s %s", -len(strconv.Itoa(len(history))), i+1, -idWidth, todo.ShortID, -maxName, todo.Name, when))
+		}
+	case LISTS:
+		lists := ui.lists()
+		maxName := 0
+		for _, list := range lists {
+			if len(list.name) > maxName {
+				maxName = len(list.name)
+			}
+		}
+		for i, list := range lists {
+			active := ' '
+			if list.name == ui.list {
+				active = '*'
+			}
+			ui.print(0, i, fmt.Sprintf("%c %*s %d todos, %d triggers", active, -maxName, list.name, list.todos, list.triggers))
+		}
+	case REPORT:
+		now := time.Now()
+		from, _ := parseRange(ui.reportRange, now)
+		var todos []Todo
+		for _, items := range [][]Todo{ui.todos, ui.history} {
+			for _, todo := range items {
+				if todo.List == ui.list {
+					todos = append(todos, todo)
+				}
+			}
+		}
+		byTodo, byTag := report(todos, from, now)
+		var total time.Duration
+		for _, e := range byTodo {
+			total += e.total
+		}
+		maxName := 0
+		for _, e := range append(byTodo, byTag...) {
+			if len(e.name) > maxName {
+				maxName = len(e.name)
+			}
+		}
+		y := 0
+		ui.print(0, y, fmt.Sprintf("%s: %s", ui.reportRange, formatElapsed(total)))
+		for _, section := range []struct {
+			title   string
+			entries []reportEntry
+		}{{"todos", byTodo}, {"tags", byTag}} {
+			if len(section.entries) == 0 {
+				continue
+			}
+			y += 2
+			ui.print(0, y, section.title)
+			for _, e := range section.entries {
+				y++
+				ui.print(0, y, fmt.Sprintf("  %*s %s", -maxName, e.name, formatElapsed(e.total)))
+			}
 		}
 	}
-	if ui.err != nil {
-		_, h := termbox.Size()
-		ui.print(0, h-2, ui.err.Error())
+
+	pattern := ui.pattern()
+	if ui.blinkt != nil && ui.blinktPattern != pattern {
+		ui.blinkt.Stop()
+		ui.blinkt = nil
+	}
+	if ui.blinkt == nil && pattern != PatternNone {
+		ui.blinkt = NewBlinkt(pattern)
+		ui.blinktPattern = pattern
+	}
+	w, h := termbox.Size()
+	if ui.msg != "" {
+		ui.print(0, h-2, ui.msg)
+	}
+	if pomo := ui.pomo; pomo != nil {
+		if todo, ok := ui.todo(pomo.todoID); ok {
+			status := pomo.status(todo.Name, time.Now())
+			ui.print(w-runewidth.StringWidth(status), h-2, status)
+		}
 	}
 	ui.cl.Redraw()
 	termbox.Flush()
@@ -141,135 +481,599 @@ func (ui *UI) Close() {
 	termbox.Close()
 }
 
-var errInvalidTime = errors.New("invalid time")
+// edited returns trigger orig updated with definition of trigger. State which
+// can't be expressed by the add command is preserved.
+func edited(orig, trigger Trigger) Trigger {
+	trigger.ID = orig.ID
+	trigger.List = orig.List
+	trigger.Paused = orig.Paused
+	trigger.ResumeAt = orig.ResumeAt
+	trigger.Replay = orig.Replay
+	trigger.ShortID = orig.ShortID
+	trigger.Source = orig.Source
+	trigger.SourceKind = orig.SourceKind
+	trigger.Snoozed = orig.Snoozed
+	if !orig.OneTime() && !trigger.OneTime() {
+		trigger.After = orig.After
+	}
+	return trigger
+}
 
-func (ui *UI) parseTime(input []byte) (time.Time, error) {
-	if len(input) == 0 {
-		return time.Time{}, errInvalidTime
+// blockers returns names of todos which block todo, including snoozed ones.
+func (ui *UI) blockers(todo Todo) []string {
+	var names []string
+	for _, id := range todo.BlockedBy {
+		for _, t := range ui.todos {
+			if t.ID == id {
+				names = append(names, t.Name)
+			}
+		}
+		for _, t := range ui.triggers {
+			for i, snoozed := range t.Snoozed {
+				if snoozed != id {
+					continue
+				}
+				if i == 0 {
+					names = append(names, t.Name)
+				} else if i <= len(t.Subtasks) {
+					names = append(names, t.Subtasks[i-1])
+				}
+			}
+		}
 	}
-	if input[0] == '+' {
-		re := regexp.MustCompile(`(\d+)([smhd])`)
-		match := re.FindSubmatch(input[1:])
-		if match == nil {
-			return time.Time{}, errInvalidTime
+	return names
+}
+
+// alerting reports whether there are todos to notify about. Blocked todos
+// can't be started, so they're ignored.
+func (ui *UI) alerting() bool {
+	for _, todo := range ui.todos {
+		if len(ui.blockers(todo)) == 0 {
+			return true
 		}
-		num, err := strconv.Atoi(string(match[1]))
-		if err != nil {
-			return time.Time{}, err
-		}
-		var unit time.Duration
-		switch string(match[2]) {
-		case "s":
-			unit = time.Second
-		case "m":
-			unit = time.Minute
-		case "h":
-			unit = time.Hour
-		case "d":
-			unit = time.Hour * 24
-		default:
-			return time.Time{}, errInvalidTime
+	}
+	return false
+}
+
+// overdue reports whether any todo which isn't blocked is past its due date.
+func (ui *UI) overdue(now time.Time) bool {
+	for _, todo := range ui.todos {
+		if !todo.Due.IsZero() && todo.Due.Before(now) && len(ui.blockers(todo)) == 0 {
+			return true
 		}
-		return time.Now().Add(unit * time.Duration(num)), nil
-	} else if input[0] == '@' {
-		now := time.Now()
-		t, err := time.ParseInLocation("15:04", string(input[1:]), now.Location())
-		t = t.AddDate(now.Year(), int(now.Month())-1, now.Day()-1)
-		return t, err
 	}
-	return time.Time{}, errInvalidTime
+	return false
 }
 
-func (ui *UI) getIdxs(token string) ([]int, error) {
-	if token == "*" {
-		length := 0
-		switch ui.view {
-		case TODOS:
-			length = len(ui.todos)
-		case TRIGGERS:
-			length = len(ui.triggers)
-		default:
-			panic("view not supported")
+// pattern returns Blinkt! pattern for the current state. Pomodoro takes
+// precedence over notifications about todos.
+func (ui *UI) pattern() Pattern {
+	if pomo := ui.pomo; pomo != nil {
+		if pomo.working {
+			return PatternWork
+		}
+		return PatternBreak
+	}
+	if ui.alerting() {
+		if ui.overdue(time.Now()) {
+			return PatternUrgent
+		}
+		return PatternAlert
+	}
+	return PatternNone
+}
+
+// todo returns todo with the given ID.
+func (ui *UI) todo(id string) (Todo, bool) {
+	for _, todo := range ui.todos {
+		if todo.ID == id {
+			return todo, true
+		}
+	}
+	return Todo{}, false
+}
+
+// nextPomoPeriod switches pomodoro to the next period. Todo gets its
+// pomodoro counted once work period is over.
+func (ui *UI) nextPomoPeriod(now time.Time) {
+	todo, ok := ui.todo(ui.pomo.todoID)
+	if !ok {
+		ui.pomo = nil
+		ui.Redraw()
+		return
+	}
+	if ui.pomo.working {
+		// Scheduler may be sending update to this goroutine.
+		go func() { ui.Scheduler.PomodoroDoneCh <- todo.ID }()
+		ui.showMsg(fmt.Sprintf("pomodoro #%d done, take a break", todo.Pomodoros+1))
+	} else {
+		ui.showMsg("break is over")
+	}
+	ui.pomo = ui.pomo.next(now)
+	ui.Redraw()
+}
+
+// tracking reports whether work on any todo is in progress.
+func (ui *UI) tracking() bool {
+	for _, todo := range ui.todos {
+		if todo.Tracking() {
+			return true
+		}
+	}
+	return false
+}
+
+// untilDue returns time left until the nearest todo becomes overdue.
+func (ui *UI) untilDue(now time.Time) (time.Duration, bool) {
+	var next time.Time
+	for _, todo := range ui.todos {
+		if todo.Due.After(now) && (next.IsZero() || todo.Due.Before(next)) {
+			next = todo.Due
+		}
+	}
+	if next.IsZero() {
+		return 0, false
+	}
+	return next.Sub(now), true
+}
+
+// scheduledMsg tells when one-time trigger fires. Day is given by its name
+// if it's within a week. Time which has passed fires right away.
+func scheduledMsg(t, now time.Time) string {
+	t = t.In(now.Location())
+	if !t.After(now) {
+		return "due now, " + t.Format("15:04") + " has passed"
+	}
+	if t.Sub(now) < 6*24*time.Hour {
+		return "scheduled for " + t.Format("Mon 15:04")
+	}
+	return "scheduled for " + t.Format("Mon 2006-01-02 15:04")
+}
+
+// formatDuration returns d in a short form like "+90m".
+func formatDuration(d time.Duration) string {
+	sign := "+"
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	switch {
+	case d%(time.Hour*24) == 0:
+		return fmt.Sprintf("%s%dd", sign, d/(time.Hour*24))
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%s%dh", sign, d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%s%dm", sign, d/time.Minute)
+	default:
+		return fmt.Sprintf("%s%ds", sign, d/time.Second)
+	}
+}
+
+// hasTags reports whether all wanted tags are present in tags.
+func hasTags(tags, wanted []string) bool {
+	for _, w := range wanted {
+		found := false
+		for _, tag := range tags {
+			if tag == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// visibleTodos returns todos from the active list matching the filter set
+// by :filter. Subtasks are placed right after their parent.
+func (ui *UI) visibleTodos() []Todo {
+	byID := make(map[string]Todo, len(ui.todos))
+	for _, todo := range ui.todos {
+		byID[todo.ID] = todo
+	}
+	now := time.Now()
+	var todos []Todo
+	for _, todo := range ui.todos {
+		if _, ok := byID[todo.Parent]; ok {
+			continue
+		}
+		if todo.List != ui.list || !ui.filtered(ui.todoSubject(todo, now)) {
+			continue
+		}
+		todos = append(todos, todo)
+		for _, id := range todo.Subtasks {
+			if sub, ok := byID[id]; ok {
+				todos = append(todos, sub)
+			}
+		}
+	}
+	return todos
+}
+
+// snoozedWithParent reports whether todo is a subtask whose parent is among
+// snoozed todos, so it's snoozed as a part of the parent.
+func (ui *UI) snoozedWithParent(todo Todo, visible []Todo, idxs []int) bool {
+	for _, idx := range idxs {
+		if visible[idx].ID == todo.Parent {
+			return true
+		}
+	}
+	return false
+}
+
+// subtasksLeft returns number of subtasks of todo which are not done yet.
+func (ui *UI) subtasksLeft(todo Todo) int {
+	left := 0
+	for _, t := range ui.todos {
+		if t.Parent == todo.ID {
+			left++
+		}
+	}
+	return left
+}
+
+// filtered reports whether item described by s matches the filter.
+func (ui *UI) filtered(s *subject) bool {
+	return ui.filter == nil || ui.filter(s)
+}
+
+// todoSubject returns properties of todo which filter is evaluated against.
+func (ui *UI) todoSubject(todo Todo, now time.Time) *subject {
+	return &subject{
+		now:       now,
+		tags:      todo.Tags,
+		priority:  todo.Priority,
+		createdAt: todo.CreatedAt,
+		due:       todo.Due,
+		blocked:   len(ui.blockers(todo)) > 0,
+		kind:      todo.TriggerKind,
+	}
+}
+
+// triggerSubject returns properties of trigger which filter is evaluated
+// against.
+func (ui *UI) triggerSubject(trigger Trigger, now time.Time) *subject {
+	s := &subject{
+		now:      now,
+		tags:     trigger.Tags,
+		priority: trigger.Priority,
+		paused:   trigger.Paused,
+		kind:     triggerKind(trigger),
+	}
+	if !trigger.Paused {
+		s.next = trigger.Next()
+	}
+	return s
+}
+
+// visibleTriggers returns triggers from the active list matching the filter
+// set by :filter.
+func (ui *UI) visibleTriggers() []Trigger {
+	now := time.Now()
+	var triggers []Trigger
+	for _, trigger := range ui.triggers {
+		if trigger.List == ui.list && ui.filtered(ui.triggerSubject(trigger, now)) {
+			triggers = append(triggers, trigger)
+		}
+	}
+	return triggers
+}
+
+// visibleHistory returns todos from the active list completed on the day
+// selected by :history.
+func (ui *UI) visibleHistory() []Todo {
+	var history []Todo
+	for _, todo := range ui.history {
+		if todo.List != ui.list {
+			continue
+		}
+		if !ui.historyDay.IsZero() {
+			y1, m1, d1 := todo.CompletedAt.Date()
+			y2, m2, d2 := ui.historyDay.Date()
+			if y1 != y2 || m1 != m2 || d1 != d2 {
+				continue
+			}
 		}
-		idxs := make([]int, length, length)
-		for i := 0; i < length; i++ {
-			idxs[i] = i
+		history = append(history, todo)
+	}
+	return history
+}
+
+// A listStats holds number of items in a list.
+type listStats struct {
+	name     string
+	todos    int
+	triggers int
+}
+
+// lists returns all lists sorted by name. Active list is always included.
+func (ui *UI) lists() []listStats {
+	stats := map[string]*listStats{ui.list: {name: ui.list}}
+	get := func(name string) *listStats {
+		if _, ok := stats[name]; !ok {
+			stats[name] = &listStats{name: name}
 		}
-		return idxs, nil
+		return stats[name]
+	}
+	for _, todo := range ui.todos {
+		get(todo.List).todos++
+	}
+	for _, trigger := range ui.triggers {
+		get(trigger.List).triggers++
 	}
-	idx, err := strconv.Atoi(token)
+	lists := make([]listStats, 0, len(stats))
+	for _, s := range stats {
+		lists = append(lists, *s)
+	}
+	sort.Slice(lists, func(i, j int) bool { return lists[i].name < lists[j].name })
+	return lists
+}
+
+var errInvalidDay = errors.New("invalid day")
+
+// parseDay returns the start of the day described by input.
+func parseDay(input string) (time.Time, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch input {
+	case "today":
+		return today, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", input, now.Location())
 	if err != nil {
-		return nil, fmt.Errorf("invalid index: %w", err)
+		return time.Time{}, errInvalidDay
 	}
-	length := 0
+	return t, nil
+}
+
+// looksLikeCron reports whether spec is rather meant to be a cron schedule
+// than time.
+func looksLikeCron(spec string) bool {
+	return len(strings.Fields(spec)) >= 5 || (strings.HasPrefix(spec, "@") && !strings.ContainsAny(spec, "0123456789"))
+}
+
+// splitSelector splits arguments into time and an optional selector
+// following it. Selector defaults to the target of command.
+func (ui *UI) splitSelector(args []string) (string, string) {
+	if len(args) > 1 {
+		when := strings.Join(args[:len(args)-1], " ")
+		selector := args[len(args)-1]
+		if _, err := timeparse.Parse(when, time.Now()); err == nil {
+			if _, err := ui.getIdxs(selector); err == nil {
+				return when, selector
+			}
+		}
+	}
+	return strings.Join(args, " "), ui.target
+}
+
+// viewItems returns items listed in the active view.
+func (ui *UI) viewItems() []selectable {
+	var items []selectable
 	switch ui.view {
 	case TODOS:
-		length = len(ui.todos)
+		for _, todo := range ui.visibleTodos() {
+			items = append(items, selectable{todo.ShortID, todo.Name})
+		}
 	case TRIGGERS:
-		length = len(ui.triggers)
+		for _, trigger := range ui.visibleTriggers() {
+			items = append(items, selectable{trigger.ShortID, trigger.Name})
+		}
+	case HISTORY:
+		for _, todo := range ui.visibleHistory() {
+			items = append(items, selectable{todo.ShortID, todo.Name})
+		}
 	default:
 		panic("view not supported")
 	}
-	if idx < 1 || idx > length {
-		return nil, errors.New("index out of range")
+	return items
+}
+
+// idWidth returns the number of digits of the longest short ID listed in the
+// active view.
+func (ui *UI) idWidth() int {
+	width := 0
+	for _, item := range ui.viewItems() {
+		if w := len(strconv.Itoa(item.id)); w > width {
+			width = w
+		}
+	}
+	return width
+}
+
+// updateTodos applies fn to scheduler's copies of todos with given IDs.
+func (ui *UI) updateTodos(ids []string, fn func(todo *Todo)) {
+	updates := make([]TodoUpdate, 0, len(ids))
+	for _, id := range ids {
+		updates = append(updates, TodoUpdate{ID: id, Update: fn})
+	}
+	ui.Scheduler.UpdateTodosCh <- updates
+}
+
+// updateTriggers applies fn to scheduler's copies of triggers with given IDs.
+func (ui *UI) updateTriggers(ids []string, fn func(trigger *Trigger)) {
+	updates := make([]TriggerUpdate, 0, len(ids))
+	for _, id := range ids {
+		updates = append(updates, TriggerUpdate{ID: id, Update: fn})
+	}
+	ui.Scheduler.UpdateTriggersCh <- updates
+}
+
+// todoIDs returns IDs of visible todos at given indexes.
+func (ui *UI) todoIDs(idxs []int) []string {
+	visible := ui.visibleTodos()
+	ids := make([]string, 0, len(idxs))
+	for _, idx := range idxs {
+		ids = append(ids, visible[idx].ID)
+	}
+	return ids
+}
+
+// triggerIDs returns IDs of visible triggers at given indexes.
+func (ui *UI) triggerIDs(idxs []int) []string {
+	visible := ui.visibleTriggers()
+	ids := make([]string, 0, len(idxs))
+	for _, idx := range idxs {
+		ids = append(ids, visible[idx].ID)
+	}
+	return ids
+}
+
+// setFilter shows only todos and triggers matching query. Empty query
+// removes the filter.
+func (ui *UI) setFilter(query string) error {
+	if query == "" {
+		ui.filter = nil
+		ui.filterText = ""
+		return nil
+	}
+	q, err := parseQuery(query)
+	if err != nil {
+		return err
 	}
-	return []int{idx - 1}, nil
+	ui.filter = q
+	ui.filterText = query
+	return nil
+}
+
+// getIdxs returns indexes of items in the active view chosen by selector.
+// See parseSelector for the syntax.
+func (ui *UI) getIdxs(selector string) ([]int, error) {
+	return parseSelector(selector, ui.viewItems())
+}
+
+// confirmed reports whether command given by tokens can act on items chosen
+// by selector tokens[at]. If selector matches names of multiple items, user
+// is asked to confirm the command first and false is returned.
+func (ui *UI) confirmed(tokens []string, at int, idxs []int) bool {
+	if len(idxs) < 2 || !hasText(tokens[at]) {
+		return true
+	}
+	items := ui.viewItems()
+	ids := make([]string, 0, len(idxs))
+	names := make([]string, 0, len(idxs))
+	for _, idx := range idxs {
+		ids = append(ids, "#"+strconv.Itoa(items[idx].id))
+		names = append(names, items[idx].name)
+	}
+	// Matched items are confirmed, not the ones matching later.
+	ui.pending = append([]string{}, tokens...)
+	ui.pending[at] = strings.Join(ids, ",")
+	ui.showMsg(fmt.Sprintf("%d items match: %s. Confirm with :y", len(idxs), strings.Join(names, ", ")))
+	return false
 }
 
 func (ui *UI) HandleCommand(tokens []string) {
-	ui.clearErr()
+	ui.clearMsg()
+	editing := ui.editing
+	ui.editing = ""
+	pending := ui.pending
+	ui.pending = nil
+	// Search match is the default target of command which ends search.
+	ui.target = "1"
+	if ui.selected != 0 {
+		ui.target = "#" + strconv.Itoa(ui.selected)
+	}
+	ui.searchQuery = ""
+	ui.selected = 0
+	ui.scroll = 0
+	if tokens[0] == "y" || tokens[0] == "yes" {
+		if pending == nil {
+			ui.showErr(errors.New("nothing to confirm"))
+			return
+		}
+		tokens = pending
+	}
 	switch tokens[0] {
 	case "a", "add":
-		if len(tokens) < 3 {
-			ui.showErr(errors.New("not enough arguments"))
+		args, err := parseAddArgs(tokens)
+		if err != nil {
+			ui.showErr(err)
 			return
 		}
-		var trigger Trigger
-		t, err := ui.parseTime([]byte(tokens[1]))
+		trigger, err := args.newTrigger(time.Now())
 		if err != nil {
-			trigger, err = NewTrigger(
-				tokens[2],
-				tokens[1],
-				time.Now(),
-				-1, // trigger indefinitely.
-			)
-			if err != nil {
-				ui.showErr(err)
-				return
+			ui.showErr(err)
+			return
+		}
+		trigger.List = ui.list
+		if editing != "" {
+			found := false
+			for _, orig := range ui.triggers {
+				found = found || orig.ID == editing
 			}
-		} else {
-			trigger, err = NewTrigger(
-				tokens[2],
-				"*/1 * * * * *",
-				t,
-				1, // one-time trigger.
-			)
-			if err != nil {
-				ui.showErr(err)
+			if !found {
+				ui.showErr(errors.New("edited trigger no longer exists"))
 				return
 			}
+			waiting := args.when == ""
+			ui.updateTriggers([]string{editing}, func(orig *Trigger) {
+				updated := trigger
+				if waiting && orig.Every != 0 {
+					// Keep waiting for todo to be done.
+					updated.After, updated.Count = orig.After, orig.Count
+				}
+				*orig = edited(*orig, updated)
+			})
+		} else {
+			ui.Scheduler.AddTriggersCh <- []Trigger{trigger}
+		}
+		if trigger.OneTime() {
+			ui.showMsg(scheduledMsg(trigger.After, time.Now()))
 		}
-		ui.Scheduler.AddTriggersCh <- []Trigger{trigger}
+	case "e", "edit":
+		if ui.view != TRIGGERS {
+			ui.showErr(errors.New("invalid command"))
+			return
+		}
+		if len(tokens) == 1 {
+			tokens = append(tokens, ui.target)
+		}
+		idxs, err := ui.getIdxs(tokens[1])
+		if err != nil {
+			ui.showErr(err)
+			return
+		}
+		if len(idxs) != 1 {
+			ui.showErr(errors.New("only one trigger can be edited at a time"))
+			return
+		}
+		trigger := ui.visibleTriggers()[idxs[0]]
+		ui.editing = trigger.ID
+		ui.cl.Fill(gsq.Join(addTokens(trigger, time.Now())...))
 	case "r", "rm":
+		if ui.view != TODOS && ui.view != TRIGGERS {
+			ui.showErr(errors.New("invalid command"))
+			return
+		}
 		if len(tokens) == 1 {
-			tokens = append(tokens, "1")
+			tokens = append(tokens, ui.target)
 		}
 		idxs, err := ui.getIdxs(tokens[1])
 		if err != nil {
 			ui.showErr(err)
 			return
 		}
+		if !ui.confirmed(tokens, 1, idxs) {
+			return
+		}
 		switch ui.view {
 		case TODOS:
+			todos := ui.visibleTodos()
 			ids := make([]string, 0, len(idxs))
 			for _, idx := range idxs {
-				ids = append(ids, ui.todos[idx].ID)
+				ids = append(ids, todos[idx].ID)
 			}
 			ui.Scheduler.DelTodosCh <- ids
 		case TRIGGERS:
+			triggers := ui.visibleTriggers()
 			ids := make([]string, 0, len(idxs))
 			for _, idx := range idxs {
-				ids = append(ids, ui.triggers[idx].ID)
+				ids = append(ids, triggers[idx].ID)
 			}
 			ui.Scheduler.DelTriggersCh <- ids
 		default:
@@ -284,26 +1088,31 @@ func (ui *UI) HandleCommand(tokens []string) {
 			ui.showErr(errors.New("not enough arguments"))
 			return
 		}
-		t, err := ui.parseTime([]byte(tokens[1]))
+		when, selector := ui.splitSelector(tokens[1:])
+		t, err := timeparse.Parse(when, time.Now())
 		if err != nil {
 			ui.showErr(err)
 			return
 		}
-		if len(tokens) == 2 {
-			tokens = append(tokens, "1")
-		}
-		idxs, err := ui.getIdxs(tokens[2])
+		idxs, err := ui.getIdxs(selector)
 		if err != nil {
 			ui.showErr(err)
 			return
 		}
+		if !ui.confirmed(tokens, len(tokens)-1, idxs) {
+			return
+		}
+		visible := ui.visibleTodos()
 		todos := make([]string, 0, len(idxs))
 		triggers := make([]Trigger, 0, len(idxs))
 		for _, idx := range idxs {
-			todo := ui.todos[idx]
+			todo := visible[idx]
+			if ui.snoozedWithParent(todo, visible, idxs) {
+				continue
+			}
 			trigger, err := NewTrigger(
 				todo.Name,
-				"*/1 * * * * *",
+				onceCron,
 				t,
 				1, // one-time trigger.
 			)
@@ -311,11 +1120,403 @@ func (ui *UI) HandleCommand(tokens []string) {
 				ui.showErr(err)
 				return
 			}
+			trigger.Priority = todo.Priority
+			trigger.Tags = todo.Tags
+			trigger.List = todo.List
+			trigger.Source = todo.TriggerID
+			trigger.SourceKind = todo.TriggerKind
+			trigger.Snoozed = []string{todo.ID}
+			for _, t := range ui.todos {
+				if t.Parent == todo.ID {
+					trigger.Subtasks = append(trigger.Subtasks, t.Name)
+					trigger.Snoozed = append(trigger.Snoozed, t.ID)
+				}
+			}
+			if !todo.Due.IsZero() {
+				trigger.DueIn = todo.Due.Sub(trigger.Next())
+			}
 			todos = append(todos, todo.ID)
 			triggers = append(triggers, trigger)
 		}
-		ui.Scheduler.DelTodosCh <- todos
-		ui.Scheduler.AddTriggersCh <- triggers
+		ui.Scheduler.SnoozeCh <- Snooze{Todos: todos, Triggers: triggers}
+		ui.showMsg(scheduledMsg(t, time.Now()))
+	case "d", "done":
+		if ui.view != TODOS {
+			ui.showErr(errors.New("invalid command"))
+			return
+		}
+		if len(tokens) == 1 {
+			tokens = append(tokens, ui.target)
+		}
+		idxs, err := ui.getIdxs(tokens[1])
+		if err != nil {
+			ui.showErr(err)
+			return
+		}
+		if !ui.confirmed(tokens, 1, idxs) {
+			return
+		}
+		todos := ui.visibleTodos()
+		ids := make([]string, 0, len(idxs))
+		for _, idx := range idxs {
+			ids = append(ids, todos[idx].ID)
+		}
+		ui.Scheduler.DoneTodosCh <- ids
+	case "h", "history":
+		day := time.Time{}
+		if len(tokens) > 1 {
+			var err error
+			day, err = parseDay(tokens[1])
+			if err != nil {
+				ui.showErr(err)
+				return
+			}
+		}
+		ui.historyDay = day
+		ui.view = HISTORY
+		ui.Redraw()
+	case "p", "prio":
+		if ui.view != TODOS && ui.view != TRIGGERS {
+			ui.showErr(errors.New("invalid command"))
+			return
+		}
+		if len(tokens) < 2 {
+			ui.showErr(errors.New("not enough arguments"))
+			return
+		}
+		priority, err := parsePriority(tokens[1])
+		if err != nil {
+			ui.showErr(err)
+			return
+		}
+		if len(tokens) == 2 {
+			tokens = append(tokens, ui.target)
+		}
+		idxs, err := ui.getIdxs(tokens[2])
+		if err != nil {
+			ui.showErr(err)
+			return
+		}
+		switch ui.view {
+		case TODOS:
+			ui.updateTodos(ui.todoIDs(idxs), func(todo *Todo) { todo.Priority = priority })
+		case TRIGGERS:
+			ui.updateTriggers(ui.triggerIDs(idxs), func(trigger *Trigger) { trigger.Priority = priority })
+		}
+	case "f", "filter":
+		if err := ui.setFilter(strings.Join(tokens[1:], " ")); err != nil {
+			ui.showErr(err)
+			return
+		}
+		ui.Redraw()
+	case "view":
+		if len(tokens) == 1 {
+			names := make([]string, 0, len(ui.views))
+			for name := range ui.views {
+				names = append(names, name)
+			}
+			if len(names) == 0 {
+				ui.showMsg("no saved views")
+				return
+			}
+			sort.Strings(names)
+			ui.showMsg("views: " + strings.Join(names, ", "))
+			return
+		}
+		switch tokens[1] {
+		case "save":
+			if len(tokens) < 3 {
+				ui.showErr(errors.New("not enough arguments"))
+				return
+			}
+			// Such views couldn't be opened.
+			if tokens[2] == "save" || tokens[2] == "rm" {
+				ui.showErr(errors.New("reserved view name: " + tokens[2]))
+				return
+			}
+			// Without query the current filter is saved.
+			query := strings.Join(tokens[3:], " ")
+			if query == "" {
+				query = ui.filterText
+			}
+			if _, err := parseQuery(query); err != nil {
+				ui.showErr(err)
+				return
+			}
+			ui.Scheduler.SaveViewCh <- SavedView{Name: tokens[2], Query: query}
+		case "rm":
+			if len(tokens) < 3 {
+				ui.showErr(errors.New("not enough arguments"))
+				return
+			}
+			ui.Scheduler.SaveViewCh <- SavedView{Name: tokens[2]}
+		default:
+			query, ok := ui.views[tokens[1]]
+			if !ok {
+				ui.showErr(errors.New("no such view: " + tokens[1]))
+				return
+			}
+			if err := ui.setFilter(query); err != nil {
+				ui.showErr(err)
+				return
+			}
+			if ui.view != TRIGGERS {
+				ui.view = TODOS
+			}
+			ui.Redraw()
+		}
+	case "l", "list":
+		list := defaultList
+		if len(tokens) > 1 {
+			list = tokens[1]
+		}
+		ui.list = list
+		if ui.view == LISTS {
+			ui.view = TODOS
+		}
+		ui.Redraw()
+	case "ls", "lists":
+		ui.view = LISTS
+		ui.Redraw()
+	case "mv":
+		if ui.view != TODOS && ui.view != TRIGGERS {
+			ui.showErr(errors.New("invalid command"))
+			return
+		}
+		if len(tokens) < 3 {
+			ui.showErr(errors.New("not enough arguments"))
+			return
+		}
+		idxs, err := ui.getIdxs(tokens[1])
+		if err != nil {
+			ui.showErr(err)
+			return
+		}
+		switch ui.view {
+		case TODOS:
+			visible := ui.visibleTodos()
+			// Subtasks are listed under their parent, so they're moved
+			// along with it and all the other subtasks.
+			roots := make(map[string]bool, len(idxs))
+			for _, idx := range idxs {
+				root := visible[idx].ID
+				if parent := visible[idx].Parent; parent != "" {
+					root = parent
+				}
+				roots[root] = true
+			}
+			var ids []string
+			for _, todo := range ui.todos {
+				if roots[todo.ID] || roots[todo.Parent] {
+					ids = append(ids, todo.ID)
+				}
+			}
+			list := tokens[2]
+			ui.updateTodos(ids, func(todo *Todo) { todo.List = list })
+		case TRIGGERS:
+			list := tokens[2]
+			ui.updateTriggers(ui.triggerIDs(idxs), func(trigger *Trigger) { trigger.List = list })
+		}
+	case "b", "block":
+		if ui.view != TODOS {
+			ui.showErr(errors.New("invalid command"))
+			return
+		}
+		if len(tokens) < 4 || len(tokens) > 5 || tokens[2] != "on" || len(tokens) == 5 && tokens[4] != "notify" {
+			ui.showErr(errors.New("usage: block <todo> on <todo> [notify]"))
+			return
+		}
+		idxs, err := ui.getIdxs(tokens[1])
+		if err != nil {
+			ui.showErr(err)
+			return
+		}
+		blockerIdxs, err := ui.getIdxs(tokens[3])
+		if err != nil {
+			ui.showErr(err)
+			return
+		}
+		notify := len(tokens) == 5
+		visible := ui.visibleTodos()
+		todos := make(map[string]Todo, len(ui.todos))
+		for _, todo := range ui.todos {
+			todos[todo.ID] = todo
+		}
+		var blockers []string
+		for _, blockerIdx := range blockerIdxs {
+			blockers = append(blockers, visible[blockerIdx].ID)
+		}
+		ids := ui.todoIDs(idxs)
+		for _, id := range ids {
+			for _, blocker := range blockers {
+				if err := block(todos, id, blocker); err != nil {
+					ui.showErr(err)
+					return
+				}
+			}
+		}
+		ui.updateTodos(ids, func(todo *Todo) {
+			for _, blocker := range blockers {
+				todo.BlockedBy = addBlocker(todo.BlockedBy, blocker)
+			}
+			// Notification requested earlier isn't cancelled.
+			if notify {
+				todo.Notify = true
+			}
+		})
+	case "unblock":
+		if ui.view != TODOS {
+			ui.showErr(errors.New("invalid command"))
+			return
+		}
+		if len(tokens) == 1 {
+			tokens = append(tokens, ui.target)
+		}
+		idxs, err := ui.getIdxs(tokens[1])
+		if err != nil {
+			ui.showErr(err)
+			return
+		}
+		ui.updateTodos(ui.todoIDs(idxs), func(todo *Todo) { todo.BlockedBy = nil })
+	case "pause", "resume":
+		if ui.view != TRIGGERS {
+			ui.showErr(errors.New("invalid command"))
+			return
+		}
+		if len(tokens) == 1 {
+			tokens = append(tokens, ui.target)
+		}
+		idxs, err := ui.getIdxs(tokens[1])
+		if err != nil {
+			ui.showErr(err)
+			return
+		}
+		now := time.Now()
+		replay := false
+		var when []string
+		for _, token := range tokens[2:] {
+			if token == "catchup" {
+				replay = true
+				continue
+			}
+			if tokens[0] == "resume" {
+				ui.showErr(errors.New("invalid argument: " + token))
+				return
+			}
+			when = append(when, token)
+		}
+		var until time.Time
+		if len(when) > 0 {
+			until, err = timeparse.Parse(strings.Join(when, " "), now)
+			if err != nil {
+				ui.showErr(err)
+				return
+			}
+		}
+		pause := tokens[0] == "pause"
+		ui.updateTriggers(ui.triggerIDs(idxs), func(trigger *Trigger) {
+			if pause {
+				trigger.Pause(until, replay)
+			} else if trigger.Paused {
+				trigger.Resume(now, replay)
+			}
+		})
+	case "u", "undo":
+		ui.Scheduler.UndoCh <- struct{}{}
+	case "redo":
+		ui.Scheduler.RedoCh <- struct{}{}
+	case "start":
+		if ui.view != TODOS {
+			ui.showErr(errors.New("invalid command"))
+			return
+		}
+		if len(tokens) == 1 {
+			tokens = append(tokens, ui.target)
+		}
+		idxs, err := ui.getIdxs(tokens[1])
+		if err != nil {
+			ui.showErr(err)
+			return
+		}
+		if len(idxs) != 1 {
+			ui.showErr(errors.New("only one todo can be tracked at a time"))
+			return
+		}
+		now := time.Now()
+		started := ui.visibleTodos()[idxs[0]].ID
+		ids := []string{started}
+		for _, todo := range ui.todos {
+			if todo.Tracking() && todo.ID != started {
+				ids = append(ids, todo.ID)
+			}
+		}
+		ui.updateTodos(ids, func(todo *Todo) {
+			if todo.ID == started {
+				todo.StartTracking(now)
+			} else {
+				todo.StopTracking(now)
+			}
+		})
+	case "stop":
+		var ids []string
+		for _, todo := range ui.todos {
+			if todo.Tracking() {
+				ids = append(ids, todo.ID)
+			}
+		}
+		if len(ids) == 0 {
+			ui.showErr(errors.New("no todo is tracked"))
+			return
+		}
+		now := time.Now()
+		ui.updateTodos(ids, func(todo *Todo) { todo.StopTracking(now) })
+	case "pomo":
+		if len(tokens) > 1 && tokens[1] == "stop" {
+			ui.pomoCh <- nil
+			return
+		}
+		if ui.view != TODOS {
+			ui.showErr(errors.New("invalid command"))
+			return
+		}
+		selector := ui.target
+		work, rest := defaultPomoWork, defaultPomoBreak
+		for _, token := range tokens[1:] {
+			if strings.Contains(token, "/") {
+				var err error
+				work, rest, err = parsePomoLengths(token)
+				if err != nil {
+					ui.showErr(err)
+					return
+				}
+				continue
+			}
+			selector = token
+		}
+		idxs, err := ui.getIdxs(selector)
+		if err != nil {
+			ui.showErr(err)
+			return
+		}
+		if len(idxs) != 1 {
+			ui.showErr(errors.New("pomodoro runs for one todo at a time"))
+			return
+		}
+		todo := ui.visibleTodos()[idxs[0]]
+		pomo := pomodoro{todoID: todo.ID, work: work, rest: rest}
+		ui.pomoCh <- pomo.next(time.Now())
+	case "report":
+		name := "today"
+		if len(tokens) > 1 {
+			name = tokens[1]
+		}
+		if _, err := parseRange(name, time.Now()); err != nil {
+			ui.showErr(err)
+			return
+		}
+		ui.reportRange = name
+		ui.view = REPORT
+		ui.Redraw()
 	case "tr", "triggers":
 		ui.view = TRIGGERS
 		ui.Redraw()
@@ -352,10 +1553,14 @@ func (ui *UI) Run() {
 	}()
 	commandsCh := ui.cl.Run(eventsCh)
 	for {
-		command := <-commandsCh
-		if command[0] == "q" || command[0] == "quit" {
-			break
+		select {
+		case command := <-commandsCh:
+			if command[0] == "q" || command[0] == "quit" {
+				return
+			}
+			ui.HandleCommand(command)
+		case search := <-ui.cl.SearchCh:
+			ui.search(search)
 		}
-		ui.HandleCommand(command)
 	}
 }
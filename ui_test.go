@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduledMsg(t *testing.T) {
+	now := time.Date(2026, 10, 14, 10, 30, 0, 0, time.Local)
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{now.Add(time.Hour), "scheduled for Wed 11:30"},
+		{now.Add(7 * 24 * time.Hour), "scheduled for Wed 2026-10-21 10:30"},
+		{now.Add(-80 * time.Minute), "due now, 09:10 has passed"},
+		{now, "due now, 10:30 has passed"},
+	}
+	for _, test := range tests {
+		if got := scheduledMsg(test.t, now); got != test.want {
+			t.Errorf("scheduledMsg(%v) = %q, want: %q", test.t, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+)
+
+// maxUndo is the number of changes kept on the undo stack.
+const maxUndo = 100
+
+var errNothingToUndo = errors.New("nothing to undo")
+
+var errNothingToRedo = errors.New("nothing to redo")
+
+// errChangedSince is returned when items touched by change to undo or redo
+// have been modified afterwards, e.g. by a trigger which fired meanwhile.
+var errChangedSince = errors.New("items have changed since, change dropped")
+
+// A State holds items of the database keyed by ID. A nil item stands for
+// one which doesn't exist.
+type State struct {
+	Todos    map[string]*Todo
+	Triggers map[string]*Trigger
+	History  map[string]*Todo
+}
+
+// A Change records items modified by a single command, before and after it
+// was applied.
+type Change struct {
+	Before State
+	After  State
+}
+
+// empty reports whether the change doesn't modify anything.
+func (c *Change) empty() bool {
+	return len(c.After.Todos) == 0 && len(c.After.Triggers) == 0 && len(c.After.History) == 0
+}
+
+// state returns a copy of all items stored in the database.
+func (db *DB) state() State {
+	s := State{
+		Todos:    make(map[string]*Todo, len(db.Todos)),
+		Triggers: make(map[string]*Trigger, len(db.Triggers)),
+		History:  make(map[string]*Todo, len(db.History)),
+	}
+	for id, todo := range db.Todos {
+		todo := todo
+		s.Todos[id] = &todo
+	}
+	for id, trigger := range db.Triggers {
+		trigger := trigger
+		s.Triggers[id] = &trigger
+	}
+	for id, todo := range db.History {
+		todo := todo
+		s.History[id] = &todo
+	}
+	return s
+}
+
+// restore brings items from s back into the database.
+func (db *DB) restore(s State) {
+	for id, todo := range s.Todos {
+		if todo == nil {
+			delete(db.Todos, id)
+		} else {
+			db.Todos[id] = *todo
+		}
+	}
+	for id, trigger := range s.Triggers {
+		if trigger == nil {
+			delete(db.Triggers, id)
+		} else {
+			db.Triggers[id] = *trigger
+		}
+	}
+	for id, todo := range s.History {
+		if todo == nil {
+			delete(db.History, id)
+		} else {
+			db.History[id] = *todo
+		}
+	}
+}
+
+// revert returns items of the database with change from state from to
+// state to applied. Only fields which differ between the two states are
+// set, so the ones changed meanwhile by scheduler (e.g. After of trigger
+// which has fired) are kept. It reports false if any of the fields set has
+// been modified since or item has been added or removed.
+func (db *DB) revert(from, to State) (State, bool) {
+	s := State{
+		Todos:    make(map[string]*Todo, len(to.Todos)),
+		Triggers: make(map[string]*Trigger, len(to.Triggers)),
+		History:  make(map[string]*Todo, len(to.History)),
+	}
+	if !revertTodos(db.Todos, from.Todos, to.Todos, s.Todos) ||
+		!revertTodos(db.History, from.History, to.History, s.History) {
+		return s, false
+	}
+	for id := range to.Triggers {
+		var cur *Trigger
+		if trigger, ok := db.Triggers[id]; ok {
+			cur = &trigger
+		}
+		v, ok := revertItem(reflect.ValueOf(cur), reflect.ValueOf(from.Triggers[id]), reflect.ValueOf(to.Triggers[id]))
+		if !ok {
+			return s, false
+		}
+		s.Triggers[id] = v.Interface().(*Trigger)
+	}
+	return s, true
+}
+
+// revertTodos puts todos with change from state from to state to applied
+// into res, like revert does.
+func revertTodos(todos map[string]Todo, from, to, res map[string]*Todo) bool {
+	for id := range to {
+		var cur *Todo
+		if todo, ok := todos[id]; ok {
+			cur = &todo
+		}
+		v, ok := revertItem(reflect.ValueOf(cur), reflect.ValueOf(from[id]), reflect.ValueOf(to[id]))
+		if !ok {
+			return false
+		}
+		res[id] = v.Interface().(*Todo)
+	}
+	return true
+}
+
+// revertItem returns cur with fields which differ between from and to set
+// to their values in to. All of them are pointers to structs, nil if item
+// doesn't exist.
+func revertItem(cur, from, to reflect.Value) (reflect.Value, bool) {
+	if cur.IsNil() != from.IsNil() {
+		return to, false
+	}
+	// Item added or removed.
+	if from.IsNil() || to.IsNil() {
+		return to, true
+	}
+	res := reflect.New(cur.Elem().Type())
+	res.Elem().Set(cur.Elem())
+	for i := 0; i < res.Elem().NumField(); i++ {
+		f, t := from.Elem().Field(i).Interface(), to.Elem().Field(i).Interface()
+		if reflect.DeepEqual(f, t) {
+			continue
+		}
+		if !reflect.DeepEqual(cur.Elem().Field(i).Interface(), f) {
+			return to, false
+		}
+		res.Elem().Field(i).Set(to.Elem().Field(i))
+	}
+	return res, true
+}
+
+// diff returns the change turning state a into state b.
+func diff(a, b State) Change {
+	c := Change{
+		Before: State{
+			Todos:    make(map[string]*Todo),
+			Triggers: make(map[string]*Trigger),
+			History:  make(map[string]*Todo),
+		},
+		After: State{
+			Todos:    make(map[string]*Todo),
+			Triggers: make(map[string]*Trigger),
+			History:  make(map[string]*Todo),
+		},
+	}
+	diffTodos(a.Todos, b.Todos, c.Before.Todos, c.After.Todos)
+	diffTodos(a.History, b.History, c.Before.History, c.After.History)
+	for id := range a.Triggers {
+		if !reflect.DeepEqual(a.Triggers[id], b.Triggers[id]) {
+			c.Before.Triggers[id] = a.Triggers[id]
+			c.After.Triggers[id] = b.Triggers[id]
+		}
+	}
+	for id := range b.Triggers {
+		if _, ok := a.Triggers[id]; !ok {
+			c.Before.Triggers[id] = nil
+			c.After.Triggers[id] = b.Triggers[id]
+		}
+	}
+	return c
+}
+
+func diffTodos(a, b, before, after map[string]*Todo) {
+	for id := range a {
+		if !reflect.DeepEqual(a[id], b[id]) {
+			before[id] = a[id]
+			after[id] = b[id]
+		}
+	}
+	for id := range b {
+		if _, ok := a[id]; !ok {
+			before[id] = nil
+			after[id] = b[id]
+		}
+	}
+}
+
+// record applies fn to the database and puts the resulting change on the
+// undo stack.
+func (db *DB) record(fn func()) {
+	before := db.state()
+	fn()
+	change := diff(before, db.state())
+	if change.empty() {
+		return
+	}
+	db.Undo = append(db.Undo, change)
+	if len(db.Undo) > maxUndo {
+		db.Undo = db.Undo[len(db.Undo)-maxUndo:]
+	}
+	db.Redo = nil
+}
+
+// undo reverts the most recently recorded change. Change to fields modified
+// afterwards without being recorded (e.g. by scheduler) is dropped instead.
+func (db *DB) undo() error {
+	if len(db.Undo) == 0 {
+		return errNothingToUndo
+	}
+	change := db.Undo[len(db.Undo)-1]
+	db.Undo = db.Undo[:len(db.Undo)-1]
+	s, ok := db.revert(change.After, change.Before)
+	if !ok {
+		return errChangedSince
+	}
+	db.restore(s)
+	db.Redo = append(db.Redo, change)
+	return nil
+}
+
+// redo applies again the most recently reverted change. Like in undo, change
+// to items modified in the meantime is dropped.
+func (db *DB) redo() error {
+	if len(db.Redo) == 0 {
+		return errNothingToRedo
+	}
+	change := db.Redo[len(db.Redo)-1]
+	db.Redo = db.Redo[:len(db.Redo)-1]
+	s, ok := db.revert(change.Before, change.After)
+	if !ok {
+		return errChangedSince
+	}
+	db.restore(s)
+	db.Undo = append(db.Undo, change)
+	return nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestDB() *DB {
+	return &DB{
+		Todos:    make(map[string]Todo),
+		Triggers: make(map[string]Trigger),
+		History:  make(map[string]Todo),
+	}
+}
+
+func TestDiff(t *testing.T) {
+	db := newTestDB()
+	db.Todos["a"] = Todo{ID: "a", Name: "tea"}
+	db.Todos["b"] = Todo{ID: "b", Name: "coffee"}
+	db.Triggers["t"] = Trigger{ID: "t", Name: "standup"}
+	before := db.state()
+	db.Todos["a"] = Todo{ID: "a", Name: "green tea"}
+	delete(db.Todos, "b")
+	db.Todos["c"] = Todo{ID: "c", Name: "water"}
+	change := diff(before, db.state())
+
+	if len(change.Before.Todos) != 3 || len(change.After.Todos) != 3 {
+		t.Fatalf("diff touches %d todos, want: 3", len(change.After.Todos))
+	}
+	if change.Before.Todos["a"].Name != "tea" || change.After.Todos["a"].Name != "green tea" {
+		t.Errorf("modified todo: %v -> %v", change.Before.Todos["a"], change.After.Todos["a"])
+	}
+	if change.Before.Todos["b"] == nil || change.After.Todos["b"] != nil {
+		t.Errorf("removed todo: %v -> %v", change.Before.Todos["b"], change.After.Todos["b"])
+	}
+	if change.Before.Todos["c"] != nil || change.After.Todos["c"] == nil {
+		t.Errorf("added todo: %v -> %v", change.Before.Todos["c"], change.After.Todos["c"])
+	}
+	if len(change.After.Triggers) != 0 || len(change.After.History) != 0 {
+		t.Errorf("diff touches unmodified items: %v", change.After)
+	}
+	if same := diff(before, before); !same.empty() {
+		t.Error("diff of the same state isn't empty")
+	}
+}
+
+func TestUndoRedo(t *testing.T) {
+	db := newTestDB()
+	db.record(func() { db.Todos["a"] = Todo{ID: "a", Name: "tea"} })
+	db.record(func() { db.Todos["a"] = Todo{ID: "a", Name: "green tea"} })
+	db.record(func() {})
+	if len(db.Undo) != 2 {
+		t.Fatalf("got %d changes on undo stack, want: 2", len(db.Undo))
+	}
+
+	if err := db.undo(); err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if db.Todos["a"].Name != "tea" {
+		t.Errorf("got %q after undo, want: tea", db.Todos["a"].Name)
+	}
+	if err := db.undo(); err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if _, ok := db.Todos["a"]; ok {
+		t.Error("added todo still exists after undo")
+	}
+	if err := db.undo(); err != errNothingToUndo {
+		t.Errorf("undo of empty stack = %v, want: %v", err, errNothingToUndo)
+	}
+
+	if err := db.redo(); err != nil {
+		t.Fatalf("redo failed: %v", err)
+	}
+	if db.Todos["a"].Name != "tea" {
+		t.Errorf("got %q after redo, want: tea", db.Todos["a"].Name)
+	}
+	db.record(func() { delete(db.Todos, "a") })
+	if err := db.redo(); err != errNothingToRedo {
+		t.Errorf("redo after new change = %v, want: %v", err, errNothingToRedo)
+	}
+}
+
+func TestUndoChangedSince(t *testing.T) {
+	db := newTestDB()
+	db.record(func() { db.Triggers["t"] = Trigger{ID: "t", Name: "tea"} })
+	// Trigger fires and is removed by scheduler.
+	delete(db.Triggers, "t")
+	db.Todos["a"] = Todo{ID: "a", Name: "tea"}
+	if err := db.undo(); err != errChangedSince {
+		t.Errorf("undo = %v, want: %v", err, errChangedSince)
+	}
+	if len(db.Undo) != 0 || len(db.Redo) != 0 {
+		t.Errorf("stale change kept: undo %d, redo %d", len(db.Undo), len(db.Redo))
+	}
+	if _, ok := db.Todos["a"]; !ok {
+		t.Error("todo created by scheduler removed")
+	}
+
+	// Scheduler changes other fields than user did.
+	db.record(func() {
+		todo := db.Todos["a"]
+		todo.Priority = 1
+		db.Todos["a"] = todo
+	})
+	todo := db.Todos["a"]
+	todo.Repeat = 2
+	db.Todos["a"] = todo
+	if err := db.undo(); err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if todo := db.Todos["a"]; todo.Priority != 0 || todo.Repeat != 2 {
+		t.Errorf("got priority %d, repeat %d after undo, want: 0, 2", todo.Priority, todo.Repeat)
+	}
+
+	// Recurring trigger fires after being added.
+	trigger, err := NewTrigger("standup", "0 9 * * *", time.Now(), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.record(func() { db.Triggers[trigger.ID] = trigger })
+	trigger.After = trigger.After.Add(24 * time.Hour)
+	db.Triggers[trigger.ID] = trigger
+	if err := db.undo(); err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if _, ok := db.Triggers[trigger.ID]; ok {
+		t.Error("added trigger still exists after undo")
+	}
+	if err := db.redo(); err != nil {
+		t.Fatalf("redo failed: %v", err)
+	}
+	if _, ok := db.Triggers[trigger.ID]; !ok {
+		t.Error("trigger not added again by redo")
+	}
+}